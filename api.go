@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	defaultAPIPort     = "8090"
+	defaultHistoryPage = 50
+	maxHistoryPage     = 500
+)
+
+// APIServer exposes oracle status, price history, and manual-trigger
+// endpoints over REST-JSON and a minimal JSON-RPC 2.0 endpoint, plus
+// Prometheus metrics, mirroring the neo-go pattern of exposing an oracle
+// module through RPC.
+type APIServer struct {
+	updater      *OracleUpdater
+	triggerToken string
+	startedAt    time.Time
+}
+
+func NewAPIServer(updater *OracleUpdater) *APIServer {
+	return &APIServer{
+		updater:      updater,
+		triggerToken: os.Getenv("API_TRIGGER_TOKEN"),
+		startedAt:    time.Now(),
+	}
+}
+
+// Start runs the embedded HTTP server until ctx is cancelled or the
+// listener fails. It is meant to be launched from main alongside Run.
+func (s *APIServer) Start(ctx context.Context) error {
+	port := os.Getenv("API_PORT")
+	if port == "" {
+		port = defaultAPIPort
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/sources", s.handleSources)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/rpc", s.handleRPC)
+	mux.HandleFunc("/price/", s.handlePrice)
+	mux.HandleFunc("/trigger/", s.handleTrigger)
+
+	server := &http.Server{Addr: ":" + port, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("🌍 API server listening on :%s", port)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("API server failed: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("❌ Failed to encode API response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// handlePrice serves both GET /price/{symbol} (latest aggregated + last
+// on-chain price) and GET /price/{symbol}/history (paged from Postgres).
+func (s *APIServer) handlePrice(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/price/")
+	if path == "" {
+		writeError(w, http.StatusNotFound, "symbol is required")
+		return
+	}
+
+	if symbol, ok := strings.CutSuffix(path, "/history"); ok {
+		s.handlePriceHistory(w, r, symbol)
+		return
+	}
+
+	s.handleLatestPrice(w, r, path)
+}
+
+func (s *APIServer) handleLatestPrice(w http.ResponseWriter, r *http.Request, symbol string) {
+	feed := s.updater.registry.Feed(symbol)
+	if feed == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no feed configured for symbol %q", symbol))
+		return
+	}
+
+	ctx := r.Context()
+
+	resp := map[string]interface{}{
+		"symbol": symbol,
+	}
+
+	latestPrice, latestTxHash, latestAt, hasLatest, err := s.latestAggregatedPrice(ctx, symbol)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if hasLatest {
+		resp["latest_price"] = latestPrice
+		resp["latest_tx_hash"] = latestTxHash
+		resp["latest_at"] = latestAt
+	}
+
+	lastOnChainPrice, lastOnChainAt, hasOnChain, err := s.updater.policy.lastOnChainUpdate(ctx, symbol)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if hasOnChain {
+		resp["last_on_chain_price"] = lastOnChainPrice
+		resp["last_on_chain_at"] = lastOnChainAt
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// latestAggregatedPrice returns the newest aggregated price recorded for
+// symbol in price_oracle, which may be ahead of lastOnChainUpdate when
+// the most recent round was skipped or deferred to another cluster
+// leader rather than written on-chain.
+func (s *APIServer) latestAggregatedPrice(ctx context.Context, symbol string) (price float64, txHash string, createdAt time.Time, ok bool, err error) {
+	query := `
+		SELECT price_usd, tx_hash, created_at FROM price_oracle
+		WHERE symbol = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var priceDecimal decimal.Decimal
+	row := s.updater.db.QueryRow(ctx, query, symbol)
+	if scanErr := row.Scan(&priceDecimal, &txHash, &createdAt); scanErr != nil {
+		if scanErr == pgx.ErrNoRows {
+			return 0, "", time.Time{}, false, nil
+		}
+		return 0, "", time.Time{}, false, scanErr
+	}
+
+	priceFloat, _ := priceDecimal.Float64()
+	return priceFloat, txHash, createdAt, true, nil
+}
+
+func (s *APIServer) handlePriceHistory(w http.ResponseWriter, r *http.Request, symbol string) {
+	query := r.URL.Query()
+
+	from := parseTimeParam(query.Get("from"), time.Unix(0, 0))
+	to := parseTimeParam(query.Get("to"), time.Now())
+
+	page := 0
+	if v := query.Get("page"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed >= 0 {
+			page = parsed
+		}
+	}
+
+	pageSize := defaultHistoryPage
+	if v := query.Get("page_size"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 && parsed <= maxHistoryPage {
+			pageSize = parsed
+		}
+	}
+
+	rows, err := s.updater.db.Query(r.Context(), `
+		SELECT price_usd, tx_hash, created_at FROM price_oracle
+		WHERE symbol = $1 AND created_at BETWEEN $2 AND $3
+		ORDER BY created_at DESC
+		LIMIT $4 OFFSET $5
+	`, symbol, from, to, pageSize, page*pageSize)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to query history: %v", err))
+		return
+	}
+	defer rows.Close()
+
+	type entry struct {
+		PriceUSD  decimal.Decimal `json:"price_usd"`
+		TxHash    string          `json:"tx_hash"`
+		CreatedAt time.Time       `json:"created_at"`
+	}
+
+	var entries []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.PriceUSD, &e.TxHash, &e.CreatedAt); err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to scan history row: %v", err))
+			return
+		}
+		entries = append(entries, e)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"symbol":    symbol,
+		"page":      page,
+		"page_size": pageSize,
+		"entries":   entries,
+	})
+}
+
+func parseTimeParam(v string, fallback time.Time) time.Time {
+	if v == "" {
+		return fallback
+	}
+	if parsed, err := time.Parse(time.RFC3339, v); err == nil {
+		return parsed
+	}
+	return fallback
+}
+
+// handleHealth reports whether the database and Flow access node are
+// reachable, and how long it has been since the last successful on-chain
+// write across every configured feed.
+func (s *APIServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	dbOK := s.updater.db.Ping(ctx) == nil
+	flowOK := s.updater.flowClient.Ping(ctx) == nil
+
+	var lastTxAge *float64
+	for _, feed := range s.updater.registry.Feeds {
+		_, updatedAt, ok, err := s.updater.policy.lastOnChainUpdate(ctx, feed.Symbol)
+		if err != nil || !ok {
+			continue
+		}
+		age := time.Since(updatedAt).Seconds()
+		if lastTxAge == nil || age < *lastTxAge {
+			lastTxAge = &age
+		}
+	}
+
+	status := http.StatusOK
+	if !dbOK || !flowOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSON(w, status, map[string]interface{}{
+		"db_ok":                          dbOK,
+		"flow_ok":                        flowOK,
+		"uptime_seconds":                 time.Since(s.startedAt).Seconds(),
+		"last_successful_tx_age_seconds": lastTxAge,
+	})
+}
+
+// handleSources reports each feed's per-source last quote and error
+// counts, for operators auditing which feeds are flaky.
+func (s *APIServer) handleSources(w http.ResponseWriter, r *http.Request) {
+	out := map[string]map[string]SourceStat{}
+	for _, feed := range s.updater.registry.Feeds {
+		out[feed.Symbol] = feed.aggregator.Stats()
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleTrigger forces an immediate update for {symbol} outside the
+// ticker cadence, bypassing UpdatePolicy thresholds. It requires a
+// bearer token matching API_TRIGGER_TOKEN.
+func (s *APIServer) handleTrigger(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "POST required")
+		return
+	}
+
+	if s.triggerToken == "" {
+		writeError(w, http.StatusServiceUnavailable, "manual trigger disabled: API_TRIGGER_TOKEN not configured")
+		return
+	}
+
+	if !s.isAuthorized(r) {
+		writeError(w, http.StatusUnauthorized, "invalid or missing bearer token")
+		return
+	}
+
+	symbol := strings.TrimPrefix(r.URL.Path, "/trigger/")
+	feed := s.updater.registry.Feed(symbol)
+	if feed == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no feed configured for symbol %q", symbol))
+		return
+	}
+
+	go s.updater.updatePrice(feed, true)
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "triggered", "symbol": symbol})
+}
+
+func (s *APIServer) isAuthorized(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	token, ok := strings.CutPrefix(auth, "Bearer ")
+	return ok && token == s.triggerToken
+}
+
+// handleMetrics serves a small set of Prometheus gauges/counters: update
+// latency, last deviation, per-source error counts, and the gas limit
+// submitted with the last on-chain write.
+func (s *APIServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintf(w, "# HELP cron_oracle_source_errors_total Errors observed per price source\n")
+	fmt.Fprintf(w, "# TYPE cron_oracle_source_errors_total counter\n")
+	for _, feed := range s.updater.registry.Feeds {
+		for name, stat := range feed.aggregator.Stats() {
+			fmt.Fprintf(w, "cron_oracle_source_errors_total{symbol=%q,source=%q} %d\n", feed.Symbol, name, stat.ErrorCount)
+		}
+	}
+
+	fmt.Fprintf(w, "# HELP cron_oracle_source_last_quote_usd Last successful quote per source\n")
+	fmt.Fprintf(w, "# TYPE cron_oracle_source_last_quote_usd gauge\n")
+	for _, feed := range s.updater.registry.Feeds {
+		for name, stat := range feed.aggregator.Stats() {
+			if stat.SuccessCount == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "cron_oracle_source_last_quote_usd{symbol=%q,source=%q} %f\n", feed.Symbol, name, stat.LastQuote)
+		}
+	}
+
+	fmt.Fprintf(w, "# HELP cron_oracle_last_on_chain_price_usd Last price posted on-chain per symbol\n")
+	fmt.Fprintf(w, "# TYPE cron_oracle_last_on_chain_price_usd gauge\n")
+	for _, feed := range s.updater.registry.Feeds {
+		price, _, ok, err := s.updater.policy.lastOnChainUpdate(r.Context(), feed.Symbol)
+		if err != nil || !ok {
+			continue
+		}
+		fmt.Fprintf(w, "cron_oracle_last_on_chain_price_usd{symbol=%q} %f\n", feed.Symbol, price)
+	}
+
+	fmt.Fprintf(w, "# HELP cron_oracle_update_latency_seconds Seconds since the last successful on-chain write per symbol\n")
+	fmt.Fprintf(w, "# TYPE cron_oracle_update_latency_seconds gauge\n")
+	for _, feed := range s.updater.registry.Feeds {
+		_, updatedAt, ok, err := s.updater.policy.lastOnChainUpdate(r.Context(), feed.Symbol)
+		if err != nil || !ok {
+			continue
+		}
+		fmt.Fprintf(w, "cron_oracle_update_latency_seconds{symbol=%q} %f\n", feed.Symbol, time.Since(updatedAt).Seconds())
+	}
+
+	fmt.Fprintf(w, "# HELP cron_oracle_last_deviation_ratio Relative deviation between the latest aggregated price and the last on-chain price per symbol\n")
+	fmt.Fprintf(w, "# TYPE cron_oracle_last_deviation_ratio gauge\n")
+	for _, feed := range s.updater.registry.Feeds {
+		latestPrice, _, _, hasLatest, err := s.latestAggregatedPrice(r.Context(), feed.Symbol)
+		if err != nil || !hasLatest {
+			continue
+		}
+		onChainPrice, _, hasOnChain, err := s.updater.policy.lastOnChainUpdate(r.Context(), feed.Symbol)
+		if err != nil || !hasOnChain || onChainPrice == 0 {
+			continue
+		}
+		deviation := (latestPrice - onChainPrice) / onChainPrice
+		fmt.Fprintf(w, "cron_oracle_last_deviation_ratio{symbol=%q} %f\n", feed.Symbol, deviation)
+	}
+
+	fmt.Fprintf(w, "# HELP cron_oracle_last_gas_limit Gas limit submitted with the last on-chain write per symbol (Flow's access API does not report actual gas consumed)\n")
+	fmt.Fprintf(w, "# TYPE cron_oracle_last_gas_limit gauge\n")
+	for _, feed := range s.updater.registry.Feeds {
+		limit, ok := getLastGasLimit(feed.Symbol)
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, "cron_oracle_last_gas_limit{symbol=%q} %d\n", feed.Symbol, limit)
+	}
+}
+
+// jsonRPCRequest and jsonRPCResponse follow the JSON-RPC 2.0 envelope.
+type jsonRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type jsonRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *jsonRPCError   `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// handleRPC exposes the same oracle operations as the REST endpoints
+// through a minimal JSON-RPC 2.0 envelope: getPrice, getSources, and
+// health.
+func (s *APIServer) handleRPC(w http.ResponseWriter, r *http.Request) {
+	var req jsonRPCRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, jsonRPCResponse{JSONRPC: "2.0", Error: &jsonRPCError{Code: -32700, Message: "parse error"}})
+		return
+	}
+
+	resp := jsonRPCResponse{JSONRPC: "2.0", ID: req.ID}
+
+	switch req.Method {
+	case "getPrice":
+		var params struct {
+			Symbol string `json:"symbol"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			resp.Error = &jsonRPCError{Code: -32602, Message: "invalid params"}
+			break
+		}
+		price, _, ok, err := s.updater.policy.lastOnChainUpdate(r.Context(), params.Symbol)
+		if err != nil {
+			resp.Error = &jsonRPCError{Code: -32000, Message: err.Error()}
+		} else if !ok {
+			resp.Error = &jsonRPCError{Code: -32001, Message: "no on-chain price recorded for symbol"}
+		} else {
+			resp.Result = map[string]interface{}{"symbol": params.Symbol, "price_usd": price}
+		}
+	case "getSources":
+		out := map[string]map[string]SourceStat{}
+		for _, feed := range s.updater.registry.Feeds {
+			out[feed.Symbol] = feed.aggregator.Stats()
+		}
+		resp.Result = out
+	case "health":
+		resp.Result = map[string]interface{}{
+			"db_ok":   s.updater.db.Ping(r.Context()) == nil,
+			"flow_ok": s.updater.flowClient.Ping(r.Context()) == nil,
+		}
+	default:
+		resp.Error = &jsonRPCError{Code: -32601, Message: "method not found"}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
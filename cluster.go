@@ -0,0 +1,647 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb"
+	"github.com/jackc/pgx/v5/pgxpool"
+	libp2p "github.com/libp2p/go-libp2p"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+	"github.com/onflow/cadence"
+	"github.com/onflow/flow-go-sdk"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	observationGossipWindow = 2 * time.Second
+	raftApplyTimeout        = 5 * time.Second
+
+	phaseReading = "reading" // each node's own raw price reading
+	phaseConfirm = "confirm" // a node's signature over the round's agreed price
+)
+
+// ClusterConfig configures one node in a multi-node oracle cluster: its
+// Raft identity, transport and peer set for leader election, its libp2p
+// peer set for pubsub gossip, and the Ed25519 key it signs price
+// observations with.
+type ClusterConfig struct {
+	NodeID       string
+	RaftBindAddr string            // this node's own Raft TCP transport address, e.g. "10.0.0.1:7000"
+	RaftPeers    map[string]string // every other voting member's Raft ServerID -> host:port
+	Peers        []string          // libp2p multiaddrs of gossip peers, e.g. "/ip4/.../p2p/Qm..."
+	RaftDir      string
+	SignerKey    ed25519.PrivateKey
+}
+
+// loadClusterConfigFromEnv builds a ClusterConfig from NODE_ID,
+// RAFT_BIND_ADDR, RAFT_PEERS, PEERS, RAFT_DIR and SIGNER_KEY. ok is false
+// when NODE_ID is unset, meaning the updater should run in its original
+// single-process mode.
+func loadClusterConfigFromEnv() (cfg *ClusterConfig, ok bool, err error) {
+	nodeID := os.Getenv("NODE_ID")
+	if nodeID == "" {
+		return nil, false, nil
+	}
+
+	signerKeyHex := os.Getenv("SIGNER_KEY")
+	if signerKeyHex == "" {
+		return nil, false, fmt.Errorf("SIGNER_KEY is required when NODE_ID is set")
+	}
+	signerSeed, err := hex.DecodeString(signerKeyHex)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decode SIGNER_KEY: %w", err)
+	}
+	if len(signerSeed) != ed25519.SeedSize {
+		return nil, false, fmt.Errorf("SIGNER_KEY must be a %d-byte hex-encoded ed25519 seed", ed25519.SeedSize)
+	}
+
+	raftBindAddr := os.Getenv("RAFT_BIND_ADDR")
+	if raftBindAddr == "" {
+		return nil, false, fmt.Errorf("RAFT_BIND_ADDR is required when NODE_ID is set")
+	}
+
+	raftPeers, err := parseRaftPeers(os.Getenv("RAFT_PEERS"))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse RAFT_PEERS: %w", err)
+	}
+
+	raftDir := os.Getenv("RAFT_DIR")
+	if raftDir == "" {
+		raftDir = "./raft/" + nodeID
+	}
+
+	var peers []string
+	if v := os.Getenv("PEERS"); v != "" {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				peers = append(peers, p)
+			}
+		}
+	}
+
+	return &ClusterConfig{
+		NodeID:       nodeID,
+		RaftBindAddr: raftBindAddr,
+		RaftPeers:    raftPeers,
+		Peers:        peers,
+		RaftDir:      raftDir,
+		SignerKey:    ed25519.NewKeyFromSeed(signerSeed),
+	}, true, nil
+}
+
+// parseRaftPeers parses RAFT_PEERS, a comma-separated list of
+// "nodeID=host:port" entries giving every other voting member's Raft
+// transport address. This is deliberately distinct from PEERS, which
+// carries libp2p multiaddrs for pubsub gossip: Raft needs a bare TCP
+// address per ServerID, not a dialable libp2p peer.
+func parseRaftPeers(v string) (map[string]string, error) {
+	peers := map[string]string{}
+	if v == "" {
+		return peers, nil
+	}
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, addr, ok := strings.Cut(entry, "=")
+		if !ok || id == "" || addr == "" {
+			return nil, fmt.Errorf("invalid RAFT_PEERS entry %q, want nodeID=host:port", entry)
+		}
+		peers[id] = addr
+	}
+	return peers, nil
+}
+
+// PriceObservation is one node's signed vote for a symbol in a given
+// round, gossiped to every peer over libp2p pubsub. Phase distinguishes a
+// node's raw price reading from its later signature over the round's
+// agreed price, so the two can't be confused with each other.
+type PriceObservation struct {
+	NodeID    string    `json:"node_id"`
+	Symbol    string    `json:"symbol"`
+	Round     uint64    `json:"round"`
+	Phase     string    `json:"phase"`
+	Price     float64   `json:"price"`
+	PublicKey []byte    `json:"public_key"`
+	Signature []byte    `json:"signature"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func signedPayload(symbol, phase string, round uint64, price float64) []byte {
+	return []byte(fmt.Sprintf("%s|%s|%d|%.8f", symbol, phase, round, price))
+}
+
+func newPriceObservation(nodeID, symbol, phase string, round uint64, price float64, key ed25519.PrivateKey) PriceObservation {
+	payload := signedPayload(symbol, phase, round, price)
+	return PriceObservation{
+		NodeID:    nodeID,
+		Symbol:    symbol,
+		Round:     round,
+		Phase:     phase,
+		Price:     price,
+		PublicKey: key.Public().(ed25519.PublicKey),
+		Signature: ed25519.Sign(key, payload),
+		Timestamp: time.Now(),
+	}
+}
+
+func (o PriceObservation) Verify() bool {
+	return ed25519.Verify(o.PublicKey, signedPayload(o.Symbol, o.Phase, o.Round, o.Price), o.Signature)
+}
+
+// ClusterNode turns a standalone OracleUpdater into one member of a
+// threshold-signed cluster: members gossip signed price observations over
+// libp2p pubsub (one topic per symbol), agree on the round's canonical
+// price and leader via Raft, and only the leader submits the aggregated
+// value on-chain, bundling >= ceil(2N/3) member signatures over that
+// exact price.
+type ClusterNode struct {
+	config *ClusterConfig
+	host   host.Host
+	pubsub *pubsub.PubSub
+	raft   *raft.Raft
+	fsm    *oracleFSM
+	db     *pgxpool.Pool
+
+	mu     sync.Mutex
+	topics map[string]*pubsub.Topic
+}
+
+func NewClusterNode(ctx context.Context, cfg *ClusterConfig, db *pgxpool.Pool) (*ClusterNode, error) {
+	h, err := libp2p.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create libp2p host: %w", err)
+	}
+
+	ps, err := pubsub.NewGossipSub(ctx, h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gossipsub router: %w", err)
+	}
+
+	for _, addr := range cfg.Peers {
+		info, err := peerInfoFromMultiaddr(addr)
+		if err != nil {
+			log.Printf("⚠️  Skipping unparseable peer %q: %v", addr, err)
+			continue
+		}
+		if err := h.Connect(ctx, *info); err != nil {
+			log.Printf("⚠️  Failed to connect to peer %s: %v", info.ID, err)
+		}
+	}
+
+	fsm := newOracleFSM()
+
+	raftNode, err := setupRaft(cfg, fsm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up raft: %w", err)
+	}
+
+	return &ClusterNode{
+		config: cfg,
+		host:   h,
+		pubsub: ps,
+		raft:   raftNode,
+		fsm:    fsm,
+		db:     db,
+		topics: map[string]*pubsub.Topic{},
+	}, nil
+}
+
+func peerInfoFromMultiaddr(addr string) (*peer.AddrInfo, error) {
+	maddr, err := multiaddr.NewMultiaddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	return peer.AddrInfoFromP2pAddr(maddr)
+}
+
+func setupRaft(cfg *ClusterConfig, fsm raft.FSM) (*raft.Raft, error) {
+	raftConfig := raft.DefaultConfig()
+	raftConfig.LocalID = raft.ServerID(cfg.NodeID)
+
+	if err := os.MkdirAll(cfg.RaftDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create raft dir: %w", err)
+	}
+
+	logStore, err := raftboltdb.NewBoltStore(cfg.RaftDir + "/raft-log.bolt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open raft log store: %w", err)
+	}
+
+	stableStore, err := raftboltdb.NewBoltStore(cfg.RaftDir + "/raft-stable.bolt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open raft stable store: %w", err)
+	}
+
+	snapshots, err := raft.NewFileSnapshotStore(cfg.RaftDir, 2, os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create raft snapshot store: %w", err)
+	}
+
+	transport, err := raft.NewTCPTransport(cfg.RaftBindAddr, nil, 3, 10*time.Second, os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create raft transport: %w", err)
+	}
+
+	r, err := raft.NewRaft(raftConfig, fsm, logStore, stableStore, snapshots, transport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start raft node: %w", err)
+	}
+
+	servers := []raft.Server{{ID: raft.ServerID(cfg.NodeID), Address: transport.LocalAddr()}}
+	for id, addr := range cfg.RaftPeers {
+		servers = append(servers, raft.Server{ID: raft.ServerID(id), Address: raft.ServerAddress(addr)})
+	}
+	r.BootstrapCluster(raft.Configuration{Servers: servers})
+
+	return r, nil
+}
+
+func (c *ClusterNode) IsLeader() bool {
+	return c.raft.State() == raft.Leader
+}
+
+func (c *ClusterNode) quorumSize() int {
+	n := len(c.config.Peers) + 1
+	return (2*n + 2) / 3 // ceil(2n/3)
+}
+
+func (c *ClusterNode) topicFor(symbol string) (*pubsub.Topic, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if t, ok := c.topics[symbol]; ok {
+		return t, nil
+	}
+	t, err := c.pubsub.Join("cron-oracle/price/" + symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to join topic for %s: %w", symbol, err)
+	}
+	c.topics[symbol] = t
+	return t, nil
+}
+
+// ProposeRound runs the cluster's two-phase threshold protocol for
+// symbol. In the reading phase every node gossips its own raw price
+// observation; once a quorum of readings has arrived, each node
+// independently derives the same canonical price as their median. In the
+// confirm phase every node re-signs and gossips *that* agreed price, so
+// the signatures the leader ultimately bundles verify against the exact
+// value it posts on-chain rather than each node's differing raw reading.
+// It returns the confirm-phase bundle and the price it was signed over,
+// or ok=false if either phase fails to reach quorum agreement this
+// round.
+func (c *ClusterNode) ProposeRound(ctx context.Context, symbol string, price float64) (bundle []PriceObservation, agreedPrice float64, ok bool, err error) {
+	round := c.fsm.nextRound(symbol)
+
+	readings, err := c.gossipPhase(ctx, symbol, round, phaseReading, price)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if len(readings) < c.quorumSize() {
+		return nil, 0, false, nil
+	}
+
+	agreedPrice = medianObservedPrice(readings)
+
+	confirmations, err := c.gossipPhase(ctx, symbol, round, phaseConfirm, agreedPrice)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	for _, obs := range confirmations {
+		if obs.Price == agreedPrice {
+			bundle = append(bundle, obs)
+		}
+	}
+	if len(bundle) < c.quorumSize() {
+		return nil, 0, false, nil
+	}
+
+	if !c.IsLeader() {
+		return bundle, agreedPrice, true, nil
+	}
+
+	cmd, err := json.Marshal(fsmCommand{Symbol: symbol, Round: round, Price: agreedPrice})
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("failed to encode raft command: %w", err)
+	}
+	if future := c.raft.Apply(cmd, raftApplyTimeout); future.Error() != nil {
+		return nil, 0, false, fmt.Errorf("failed to commit round via raft: %w", future.Error())
+	}
+
+	return bundle, agreedPrice, true, nil
+}
+
+// gossipPhase publishes this node's signed observation of price for
+// phase and collects every peer observation matching the same
+// symbol/round/phase for a short window, keyed by node ID.
+func (c *ClusterNode) gossipPhase(ctx context.Context, symbol string, round uint64, phase string, price float64) (map[string]PriceObservation, error) {
+	topic, err := c.topicFor(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := topic.Subscribe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to topic for %s: %w", symbol, err)
+	}
+	defer sub.Cancel()
+
+	own := newPriceObservation(c.config.NodeID, symbol, phase, round, price, c.config.SignerKey)
+
+	if err := c.SaveAttestation(ctx, own); err != nil {
+		log.Printf("❌ Failed to persist own attestation: %v", err)
+	}
+
+	payload, err := json.Marshal(own)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode observation: %w", err)
+	}
+	if err := topic.Publish(ctx, payload); err != nil {
+		return nil, fmt.Errorf("failed to publish observation: %w", err)
+	}
+
+	observations := map[string]PriceObservation{c.config.NodeID: own}
+
+	collectCtx, cancel := context.WithTimeout(ctx, observationGossipWindow)
+	defer cancel()
+
+	for {
+		msg, err := sub.Next(collectCtx)
+		if err != nil {
+			break // context deadline: gossip window closed
+		}
+
+		var obs PriceObservation
+		if err := json.Unmarshal(msg.Data, &obs); err != nil {
+			log.Printf("⚠️  Dropping malformed observation from peer: %v", err)
+			continue
+		}
+		if obs.Symbol != symbol || obs.Round != round || obs.Phase != phase || !obs.Verify() {
+			continue
+		}
+
+		observations[obs.NodeID] = obs
+		if err := c.SaveAttestation(ctx, obs); err != nil {
+			log.Printf("❌ Failed to persist peer attestation: %v", err)
+		}
+	}
+
+	return observations, nil
+}
+
+// medianObservedPrice returns the median price across a set of
+// observations, the value every node in the cluster independently
+// re-derives as the round's canonical price.
+func medianObservedPrice(observations map[string]PriceObservation) float64 {
+	prices := make([]float64, 0, len(observations))
+	for _, obs := range observations {
+		prices = append(prices, obs.Price)
+	}
+	sort.Float64s(prices)
+
+	mid := len(prices) / 2
+	if len(prices)%2 == 0 {
+		return (prices[mid-1] + prices[mid]) / 2
+	}
+	return prices[mid]
+}
+
+// SaveAttestation persists a single signed observation so the quorum
+// behind any given on-chain write can be audited later.
+func (c *ClusterNode) SaveAttestation(ctx context.Context, obs PriceObservation) error {
+	query := `
+		INSERT INTO oracle_attestations (id, symbol, round, node_id, price_usd, public_key, signature, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (symbol, round, node_id) DO NOTHING
+	`
+
+	_, err := c.db.Exec(ctx, query,
+		uuid.New().String(),
+		obs.Symbol,
+		obs.Round,
+		obs.NodeID,
+		decimal.NewFromFloat(obs.Price),
+		obs.PublicKey,
+		obs.Signature,
+		obs.Timestamp,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert oracle attestation: %w", err)
+	}
+
+	return nil
+}
+
+// submitThresholdUpdate posts the round's agreed price on-chain with the
+// quorum's signature bundle attached, so the contract can verify that at
+// least quorumSize() nodes attested to this exact value before accepting
+// it.
+func (o *OracleUpdater) submitThresholdUpdate(feed *Feed, price float64, bundle []PriceObservation) (string, error) {
+	ctx := context.Background()
+
+	script := fmt.Sprintf(`
+import PriceOracle from %s
+
+transaction(newPrice: UFix64, signers: [String], signatures: [String]) {
+    prepare(signer: auth(Storage) &Account) {
+        let admin = signer.storage.borrow<&PriceOracle.Admin>(
+            from: PriceOracle.AdminStoragePath
+        ) ?? panic("Could not borrow admin resource")
+
+        admin.updatePriceWithAttestations(newPrice: newPrice, signers: signers, signatures: signatures)
+    }
+}
+`, contractAddress)
+
+	account, err := o.flowClient.GetAccount(ctx, o.account.Address)
+	if err != nil {
+		return "", fmt.Errorf("failed to get account: %w", err)
+	}
+
+	latestBlock, err := o.flowClient.GetLatestBlock(ctx, true)
+	if err != nil {
+		return "", fmt.Errorf("failed to get latest block: %w", err)
+	}
+
+	tx := flow.NewTransaction().
+		SetScript([]byte(script)).
+		SetReferenceBlockID(latestBlock.ID).
+		SetGasLimit(200).
+		SetProposalKey(o.account.Address, o.account.Keys[0].Index, account.Keys[0].SequenceNumber).
+		SetPayer(o.account.Address).
+		AddAuthorizer(o.account.Address)
+
+	priceArg, err := CadenceUFix64(price, feed.Decimals)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert price: %w", err)
+	}
+	if err := tx.AddArgument(priceArg); err != nil {
+		return "", fmt.Errorf("failed to add price argument: %w", err)
+	}
+
+	signers := make([]cadence.Value, len(bundle))
+	signatures := make([]cadence.Value, len(bundle))
+	for i, obs := range bundle {
+		signers[i] = cadence.String(obs.NodeID)
+		signatures[i] = cadence.String(hex.EncodeToString(obs.Signature))
+	}
+	if err := tx.AddArgument(cadence.NewArray(signers)); err != nil {
+		return "", fmt.Errorf("failed to add signers argument: %w", err)
+	}
+	if err := tx.AddArgument(cadence.NewArray(signatures)); err != nil {
+		return "", fmt.Errorf("failed to add signatures argument: %w", err)
+	}
+
+	if err := tx.SignEnvelope(o.account.Address, o.account.Keys[0].Index, o.signer); err != nil {
+		return "", fmt.Errorf("failed to sign transaction: %w", err)
+	}
+
+	if err := o.flowClient.SendTransaction(ctx, *tx); err != nil {
+		return "", fmt.Errorf("failed to send transaction: %w", err)
+	}
+
+	result, err := waitForSeal(ctx, o.flowClient, tx.ID())
+	if err != nil {
+		return "", fmt.Errorf("failed to wait for seal: %w", err)
+	}
+
+	if result.Error != nil {
+		return "", fmt.Errorf("transaction failed: %v", result.Error)
+	}
+
+	recordGasLimit(feed.Symbol, 200)
+	log.Printf("✅ %s threshold update posted with %d attestations (TX: %s)", feed.Symbol, len(bundle), tx.ID())
+	return tx.ID().String(), nil
+}
+
+func (c *ClusterNode) Close() error {
+	if err := c.raft.Shutdown().Error(); err != nil {
+		log.Printf("⚠️  Error shutting down raft: %v", err)
+	}
+	return c.host.Close()
+}
+
+// fsmCommand is the payload applied to the Raft log: the canonical price
+// every node agreed a given round should post on-chain.
+type fsmCommand struct {
+	Symbol string  `json:"symbol"`
+	Round  uint64  `json:"round"`
+	Price  float64 `json:"price"`
+}
+
+// oracleFSM is the Raft finite state machine that tracks the current
+// round number per symbol and the last agreed price, so every node in
+// the cluster converges on the same round even across leader changes.
+// Raft serializes calls to Apply/Snapshot/Restore against each other, but
+// nextRound is also called directly from ProposeRound on every node's own
+// goroutine, so the maps need their own lock.
+type oracleFSM struct {
+	mu     sync.Mutex
+	rounds map[string]uint64
+	agreed map[string]float64
+}
+
+func newOracleFSM() *oracleFSM {
+	return &oracleFSM{rounds: map[string]uint64{}, agreed: map[string]float64{}}
+}
+
+// nextRound proposes the next round number for symbol as one past the
+// last round Apply actually committed via Raft. rounds only ever changes
+// through Apply, which every node in the cluster replays from the same
+// replicated log, so independent nodes proposing concurrently land on
+// the same number without needing a local counter — a local
+// increment-on-every-call counter would desync permanently across a
+// restart or any scheduling skew between nodes.
+func (f *oracleFSM) nextRound(symbol string) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.rounds[symbol] + 1
+}
+
+func (f *oracleFSM) Apply(log *raft.Log) interface{} {
+	var cmd fsmCommand
+	if err := json.Unmarshal(log.Data, &cmd); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.rounds[cmd.Symbol] = cmd.Round
+	f.agreed[cmd.Symbol] = cmd.Price
+	return nil
+}
+
+func (f *oracleFSM) Snapshot() (raft.FSMSnapshot, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	rounds := make(map[string]uint64, len(f.rounds))
+	for symbol, round := range f.rounds {
+		rounds[symbol] = round
+	}
+	agreed := make(map[string]float64, len(f.agreed))
+	for symbol, price := range f.agreed {
+		agreed[symbol] = price
+	}
+
+	return &oracleFSMSnapshot{rounds: rounds, agreed: agreed}, nil
+}
+
+func (f *oracleFSM) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+	var snapshot struct {
+		Rounds map[string]uint64  `json:"rounds"`
+		Agreed map[string]float64 `json:"agreed"`
+	}
+	if err := json.NewDecoder(rc).Decode(&snapshot); err != nil {
+		return fmt.Errorf("failed to decode fsm snapshot: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.rounds = snapshot.Rounds
+	f.agreed = snapshot.Agreed
+	return nil
+}
+
+type oracleFSMSnapshot struct {
+	rounds map[string]uint64
+	agreed map[string]float64
+}
+
+func (s *oracleFSMSnapshot) Persist(sink raft.SnapshotSink) error {
+	payload := struct {
+		Rounds map[string]uint64  `json:"rounds"`
+		Agreed map[string]float64 `json:"agreed"`
+	}{Rounds: s.rounds, Agreed: s.agreed}
+
+	if err := json.NewEncoder(sink).Encode(payload); err != nil {
+		sink.Cancel()
+		return fmt.Errorf("failed to write fsm snapshot: %w", err)
+	}
+	return sink.Close()
+}
+
+func (s *oracleFSMSnapshot) Release() {}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/raft"
+)
+
+func TestQuorumSize(t *testing.T) {
+	tests := []struct {
+		name  string
+		peers int // other cluster members, not counting self
+		want  int
+	}{
+		{"solo node", 0, 1},
+		{"one peer (2 nodes total)", 1, 2},
+		{"two peers (3 nodes total)", 2, 2},
+		{"three peers (4 nodes total)", 3, 3},
+		{"four peers (5 nodes total)", 4, 4},
+		{"six peers (7 nodes total)", 6, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &ClusterNode{config: &ClusterConfig{Peers: make([]string, tt.peers)}}
+			if got := c.quorumSize(); got != tt.want {
+				t.Errorf("quorumSize() with %d peers = %d, want %d", tt.peers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMedianObservedPrice(t *testing.T) {
+	tests := []struct {
+		name   string
+		prices []float64
+		want   float64
+	}{
+		{"single", []float64{2.0}, 2.0},
+		{"odd", []float64{3, 1, 2}, 2},
+		{"even", []float64{1, 2, 3, 4}, 2.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			observations := map[string]PriceObservation{}
+			for i, p := range tt.prices {
+				observations[string(rune('a'+i))] = PriceObservation{Price: p}
+			}
+			if got := medianObservedPrice(observations); got != tt.want {
+				t.Errorf("medianObservedPrice(%v) = %v, want %v", tt.prices, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriceObservationVerify(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	obs := newPriceObservation("node1", "FLOW", phaseReading, 1, 1.2345, priv)
+	if !obs.Verify() {
+		t.Fatal("Verify() = false for an untampered observation")
+	}
+
+	tampered := obs
+	tampered.Price = 9.9999
+	if tampered.Verify() {
+		t.Error("Verify() = true for an observation with a tampered price")
+	}
+
+	reusedPhase := obs
+	reusedPhase.Phase = phaseConfirm
+	if reusedPhase.Verify() {
+		t.Error("Verify() = true for a reading signature replayed as a confirm observation")
+	}
+}
+
+func TestOracleFSMNextRoundReadsLastCommitted(t *testing.T) {
+	fsm := newOracleFSM()
+
+	if got := fsm.nextRound("FLOW"); got != 1 {
+		t.Errorf("nextRound() on a fresh fsm = %d, want 1", got)
+	}
+
+	data, err := json.Marshal(fsmCommand{Symbol: "FLOW", Round: 5, Price: 1.23})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if result := fsm.Apply(&raft.Log{Data: data}); result != nil {
+		t.Fatalf("Apply() returned %v, want nil", result)
+	}
+
+	// Every node in the cluster replays the same committed log, so once
+	// round 5 is applied, any node proposing next independently lands on
+	// 6 — no coordination beyond the already-replicated Raft state.
+	if got := fsm.nextRound("FLOW"); got != 6 {
+		t.Errorf("nextRound() after committing round 5 = %d, want 6", got)
+	}
+}
+
+func TestOracleFSMNextRoundIsSerialized(t *testing.T) {
+	fsm := newOracleFSM()
+
+	const goroutines = 50
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			fsm.nextRound("FLOW")
+		}()
+	}
+	wg.Wait()
+
+	// nextRound no longer mutates state, so concurrent calls should all
+	// observe the same un-advanced round rather than racing each other to
+	// increment it; this just confirms concurrent reads leave the round
+	// un-advanced.
+	if got := fsm.nextRound("FLOW"); got != 1 {
+		t.Errorf("nextRound() after %d concurrent reads on an uncommitted symbol = %d, want 1", goroutines, got)
+	}
+}
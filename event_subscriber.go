@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/onflow/cadence"
+	"github.com/onflow/flow-go-sdk"
+	"github.com/onflow/flow-go-sdk/client"
+)
+
+const (
+	priceRequestedEvent = "PriceOracle.PriceRequested"
+	eventPollInterval   = 5 * time.Second
+)
+
+// PriceRequestSubscriber watches the chain for PriceRequested events and
+// fulfills them immediately instead of waiting for the next ticker tick.
+// It prefers the Access API's SubscribeEvents streaming RPC and falls back
+// to polling GetEventsForHeightRange when streaming is unavailable.
+type PriceRequestSubscriber struct {
+	updater *OracleUpdater
+}
+
+func NewPriceRequestSubscriber(updater *OracleUpdater) *PriceRequestSubscriber {
+	return &PriceRequestSubscriber{updater: updater}
+}
+
+// Run blocks, watching for PriceRequested events until ctx is cancelled.
+func (s *PriceRequestSubscriber) Run(ctx context.Context) {
+	log.Println("👂 Listening for PriceRequested events")
+
+	sub, err := s.updater.flowClient.SubscribeEvents(ctx, flow.EmptyID, 0, client.EventFilter{
+		EventTypes: []string{priceRequestedEvent},
+	})
+	if err != nil {
+		log.Printf("⚠️  SubscribeEvents unavailable, falling back to polling: %v", err)
+		s.pollLoop(ctx)
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case resp, ok := <-sub:
+			if !ok {
+				log.Println("⚠️  Event subscription closed, falling back to polling")
+				s.pollLoop(ctx)
+				return
+			}
+			if resp.Error != nil {
+				log.Printf("❌ Event subscription error: %v", resp.Error)
+				continue
+			}
+			for _, e := range resp.Events {
+				s.handleEvent(ctx, e)
+			}
+		}
+	}
+}
+
+func (s *PriceRequestSubscriber) pollLoop(ctx context.Context) {
+	ticker := time.NewTicker(eventPollInterval)
+	defer ticker.Stop()
+
+	lastHeight, err := s.updater.flowClient.GetLatestBlockHeader(ctx, true)
+	if err != nil {
+		log.Printf("❌ Failed to get starting block height for event polling: %v", err)
+		return
+	}
+	fromHeight := lastHeight.Height
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			latest, err := s.updater.flowClient.GetLatestBlockHeader(ctx, true)
+			if err != nil {
+				log.Printf("❌ Failed to get latest block header: %v", err)
+				continue
+			}
+
+			if latest.Height <= fromHeight {
+				continue
+			}
+
+			blocks, err := s.updater.flowClient.GetEventsForHeightRange(ctx, client.EventRangeQuery{
+				Type:        priceRequestedEvent,
+				StartHeight: fromHeight + 1,
+				EndHeight:   latest.Height,
+			})
+			if err != nil {
+				log.Printf("❌ Failed to poll events: %v", err)
+				continue
+			}
+
+			for _, block := range blocks {
+				for _, e := range block.Events {
+					s.handleEvent(ctx, e)
+				}
+			}
+
+			fromHeight = latest.Height
+		}
+	}
+}
+
+func (s *PriceRequestSubscriber) handleEvent(ctx context.Context, e flow.Event) {
+	symbol, requestID, err := parsePriceRequestedEvent(e)
+	if err != nil {
+		log.Printf("❌ Failed to parse PriceRequested event: %v", err)
+		return
+	}
+
+	log.Printf("📨 PriceRequested: symbol=%s requestID=%s", symbol, requestID)
+
+	feed := s.updater.registry.Feed(symbol)
+	if feed == nil {
+		log.Printf("❌ No feed configured for requested symbol %q (request %s)", symbol, requestID)
+		if dbErr := s.updater.SavePriceRequest(ctx, requestID, symbol, "", fmt.Sprintf("no feed configured for symbol %q", symbol)); dbErr != nil {
+			log.Printf("❌ Failed to record failed price request: %v", dbErr)
+		}
+		return
+	}
+
+	price, err := s.updater.GetPrice(feed)
+	if err != nil {
+		log.Printf("❌ Failed to fetch price for request %s: %v", requestID, err)
+		return
+	}
+
+	txID, err := s.updater.fulfillPriceRequest(ctx, feed, requestID, price)
+	if err != nil {
+		log.Printf("❌ Failed to fulfill price request %s: %v", requestID, err)
+		if dbErr := s.updater.SavePriceRequest(ctx, requestID, symbol, "", err.Error()); dbErr != nil {
+			log.Printf("❌ Failed to record failed price request: %v", dbErr)
+		}
+		return
+	}
+
+	log.Printf("✅ Fulfilled price request %s (TX: %s)", requestID, txID)
+	if dbErr := s.updater.SavePriceRequest(ctx, requestID, symbol, txID, ""); dbErr != nil {
+		log.Printf("❌ Failed to record price request: %v", dbErr)
+	}
+}
+
+func parsePriceRequestedEvent(e flow.Event) (symbol string, requestID string, err error) {
+	cadenceEvent := e.Value
+	if cadenceEvent == nil {
+		return "", "", fmt.Errorf("event has no decoded payload")
+	}
+
+	fields := cadenceEvent.FieldsMappedByName()
+
+	symbolVal, ok := fields["symbol"]
+	if !ok {
+		return "", "", fmt.Errorf("event missing symbol field")
+	}
+	requestIDVal, ok := fields["requestID"]
+	if !ok {
+		return "", "", fmt.Errorf("event missing requestID field")
+	}
+
+	symbol, err = cadenceStringValue(symbolVal)
+	if err != nil {
+		return "", "", fmt.Errorf("event symbol field: %w", err)
+	}
+	requestID, err = cadenceStringValue(requestIDVal)
+	if err != nil {
+		return "", "", fmt.Errorf("event requestID field: %w", err)
+	}
+
+	return symbol, requestID, nil
+}
+
+// cadenceStringValue extracts the Go string underlying a Cadence String
+// value. Value.String() is not suitable here: it renders the Cadence
+// *literal* form (quoted, e.g. `"FLOW"`), which would make every
+// registry/database lookup keyed on the raw value miss.
+func cadenceStringValue(v cadence.Value) (string, error) {
+	s, ok := v.(cadence.String)
+	if !ok {
+		return "", fmt.Errorf("expected a Cadence String, got %T", v)
+	}
+	return string(s), nil
+}
+
+// fulfillPriceRequest submits the fulfillPriceRequest transaction against
+// the PriceOracle contract, answering a single on-chain PriceRequested
+// event with the freshly fetched price.
+func (o *OracleUpdater) fulfillPriceRequest(ctx context.Context, feed *Feed, requestID string, price float64) (string, error) {
+	script := fmt.Sprintf(`
+import PriceOracle from %s
+
+transaction(requestID: String, newPrice: UFix64) {
+    prepare(signer: auth(Storage) &Account) {
+        let admin = signer.storage.borrow<&PriceOracle.Admin>(
+            from: PriceOracle.AdminStoragePath
+        ) ?? panic("Could not borrow admin resource")
+
+        admin.fulfillPriceRequest(requestID: requestID, newPrice: newPrice)
+    }
+}
+`, contractAddress)
+
+	account, err := o.flowClient.GetAccount(ctx, o.account.Address)
+	if err != nil {
+		return "", fmt.Errorf("failed to get account: %w", err)
+	}
+
+	latestBlock, err := o.flowClient.GetLatestBlock(ctx, true)
+	if err != nil {
+		return "", fmt.Errorf("failed to get latest block: %w", err)
+	}
+
+	tx := flow.NewTransaction().
+		SetScript([]byte(script)).
+		SetReferenceBlockID(latestBlock.ID).
+		SetGasLimit(100).
+		SetProposalKey(o.account.Address, o.account.Keys[0].Index, account.Keys[0].SequenceNumber).
+		SetPayer(o.account.Address).
+		AddAuthorizer(o.account.Address)
+
+	if err := tx.AddArgument(cadence.String(requestID)); err != nil {
+		return "", fmt.Errorf("failed to add requestID argument: %w", err)
+	}
+
+	priceArg, err := CadenceUFix64(price, feed.Decimals)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert price: %w", err)
+	}
+	if err := tx.AddArgument(priceArg); err != nil {
+		return "", fmt.Errorf("failed to add price argument: %w", err)
+	}
+
+	if err := tx.SignEnvelope(o.account.Address, o.account.Keys[0].Index, o.signer); err != nil {
+		return "", fmt.Errorf("failed to sign transaction: %w", err)
+	}
+
+	if err := o.flowClient.SendTransaction(ctx, *tx); err != nil {
+		return "", fmt.Errorf("failed to send transaction: %w", err)
+	}
+
+	result, err := waitForSeal(ctx, o.flowClient, tx.ID())
+	if err != nil {
+		return "", fmt.Errorf("failed to wait for seal: %w", err)
+	}
+
+	if result.Error != nil {
+		return "", fmt.Errorf("transaction failed: %v", result.Error)
+	}
+
+	recordGasLimit(feed.Symbol, 100)
+	return tx.ID().String(), nil
+}
+
+// SavePriceRequest records the correlation between an on-chain
+// PriceRequested event and the transaction (if any) that fulfilled it.
+func (o *OracleUpdater) SavePriceRequest(ctx context.Context, requestID, symbol, txID, errMsg string) error {
+	query := `
+		INSERT INTO price_requests (id, request_id, symbol, tx_hash, error, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := o.db.Exec(ctx, query, uuid.New().String(), requestID, symbol, txID, errMsg, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to insert price request: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FeedConfig describes one price feed to run: which sources to poll, the
+// Cadence transaction that posts it on-chain, and the thresholds that
+// govern when an on-chain write actually fires.
+type FeedConfig struct {
+	Symbol                     string   `yaml:"symbol" json:"symbol"`
+	Sources                    []string `yaml:"sources" json:"sources"`
+	CadenceTransactionTemplate string   `yaml:"cadence_transaction_template" json:"cadence_transaction_template"`
+	Decimals                   int      `yaml:"decimals" json:"decimals"`
+	Heartbeat                  string   `yaml:"heartbeat" json:"heartbeat"`
+	DeviationThreshold         float64  `yaml:"deviation_threshold" json:"deviation_threshold"`
+	OutlierThreshold           float64  `yaml:"outlier_threshold" json:"outlier_threshold"`
+}
+
+// cadenceTemplateData is the set of fields a feed's
+// CadenceTransactionTemplate may reference.
+type cadenceTemplateData struct {
+	ContractAddress string
+	Symbol          string
+}
+
+const defaultFLOWCadenceTemplate = `
+import PriceOracle from {{.ContractAddress}}
+
+transaction(newPrice: UFix64) {
+    prepare(signer: auth(Storage) &Account) {
+        let admin = signer.storage.borrow<&PriceOracle.Admin>(
+            from: PriceOracle.AdminStoragePath
+        ) ?? panic("Could not borrow admin resource")
+
+        admin.updateFlowPrice(newPrice: newPrice)
+    }
+}
+`
+
+// defaultFeedRegistryConfig is used when no FEED_REGISTRY_PATH is
+// configured, preserving the original single-symbol FLOW/USD behavior.
+func defaultFeedRegistryConfig() []FeedConfig {
+	return []FeedConfig{
+		{
+			Symbol:                     "FLOW",
+			Sources:                    []string{"coingecko", "coinbase", "binance", "kraken", "kucoin"},
+			CadenceTransactionTemplate: defaultFLOWCadenceTemplate,
+			Decimals:                   8,
+			Heartbeat:                  defaultHeartbeatInterval.String(),
+			DeviationThreshold:         defaultDeviationThreshold,
+			OutlierThreshold:           defaultOutlierThreshold,
+		},
+	}
+}
+
+// Feed is a FeedConfig resolved into runtime components: a
+// PriceAggregator built from its named sources and a parsed heartbeat
+// duration, validated and ready to run.
+type Feed struct {
+	FeedConfig
+	aggregator *PriceAggregator
+	heartbeat  time.Duration
+}
+
+// FeedRegistry holds every feed the updater should run, each with its own
+// ticker, sources, and on-chain update policy.
+type FeedRegistry struct {
+	Feeds []*Feed
+}
+
+// Feed returns the registered feed for symbol, or nil if none is
+// configured.
+func (r *FeedRegistry) Feed(symbol string) *Feed {
+	for _, f := range r.Feeds {
+		if f.Symbol == symbol {
+			return f
+		}
+	}
+	return nil
+}
+
+// loadFeedRegistry loads the feed registry from FEED_REGISTRY_PATH if
+// set, falling back to the built-in single-feed FLOW/USD configuration
+// that preserves this updater's original behavior.
+func loadFeedRegistry() (*FeedRegistry, error) {
+	path := os.Getenv("FEED_REGISTRY_PATH")
+	if path == "" {
+		return NewFeedRegistry(defaultFeedRegistryConfig())
+	}
+	return LoadFeedRegistry(path)
+}
+
+// LoadFeedRegistry reads feed definitions from a YAML or JSON file
+// (selected by extension) and resolves each into a runnable Feed,
+// validating its Cadence transaction template along the way.
+func LoadFeedRegistry(path string) (*FeedRegistry, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed registry %s: %w", path, err)
+	}
+
+	var configs []FeedConfig
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(raw, &configs); err != nil {
+			return nil, fmt.Errorf("failed to parse feed registry YAML: %w", err)
+		}
+	case ".json":
+		if err := json.Unmarshal(raw, &configs); err != nil {
+			return nil, fmt.Errorf("failed to parse feed registry JSON: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported feed registry extension %q (want .yaml, .yml or .json)", ext)
+	}
+
+	return NewFeedRegistry(configs)
+}
+
+// NewFeedRegistry resolves a list of FeedConfig entries into a runnable
+// FeedRegistry, failing closed on the first invalid feed so a bad
+// deployment config is caught at startup rather than mid-run.
+func NewFeedRegistry(configs []FeedConfig) (*FeedRegistry, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("feed registry must declare at least one feed")
+	}
+
+	registry := &FeedRegistry{}
+
+	for _, cfg := range configs {
+		feed, err := newFeed(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("feed %q: %w", cfg.Symbol, err)
+		}
+		registry.Feeds = append(registry.Feeds, feed)
+	}
+
+	return registry, nil
+}
+
+func newFeed(cfg FeedConfig) (*Feed, error) {
+	if cfg.Symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+
+	sources, err := resolveSources(cfg.Sources)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Decimals <= 0 {
+		cfg.Decimals = 8
+	}
+	if cfg.Decimals > 8 {
+		return nil, fmt.Errorf("decimals %d exceeds the maximum of 8 supported by Cadence's UFix64", cfg.Decimals)
+	}
+
+	if cfg.DeviationThreshold <= 0 {
+		cfg.DeviationThreshold = defaultDeviationThresholdFromEnv()
+	}
+
+	if cfg.OutlierThreshold <= 0 {
+		cfg.OutlierThreshold = defaultOutlierThresholdFromEnv()
+	}
+
+	heartbeat := defaultHeartbeatIntervalFromEnv()
+	if cfg.Heartbeat != "" {
+		parsed, err := time.ParseDuration(cfg.Heartbeat)
+		if err != nil {
+			return nil, fmt.Errorf("invalid heartbeat %q: %w", cfg.Heartbeat, err)
+		}
+		heartbeat = parsed
+	}
+
+	if err := ValidateCadenceTemplate(cfg.CadenceTransactionTemplate); err != nil {
+		return nil, fmt.Errorf("invalid cadence_transaction_template: %w", err)
+	}
+
+	return &Feed{
+		FeedConfig: cfg,
+		aggregator: NewPriceAggregator(sources, cfg.OutlierThreshold),
+		heartbeat:  heartbeat,
+	}, nil
+}
+
+func resolveSources(names []string) ([]PriceSource, error) {
+	if len(names) == 0 {
+		return defaultPriceSources(), nil
+	}
+
+	available := map[string]PriceSource{}
+	for _, src := range defaultPriceSources() {
+		available[src.Name()] = src
+	}
+
+	var sources []PriceSource
+	for _, name := range names {
+		src, ok := available[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown price source %q", name)
+		}
+		sources = append(sources, src)
+	}
+
+	return sources, nil
+}
+
+// ValidateCadenceTemplate parses and renders tmpl against a representative
+// set of fields so a malformed operator-supplied template is caught at
+// startup rather than at the moment a feed tries to post on-chain.
+func ValidateCadenceTemplate(tmpl string) error {
+	if strings.TrimSpace(tmpl) == "" {
+		return fmt.Errorf("template is empty")
+	}
+
+	parsed, err := template.New("cadence").Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	if err := parsed.Execute(io.Discard, cadenceTemplateData{
+		ContractAddress: "0x0000000000000000",
+		Symbol:          "TEST",
+	}); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return nil
+}
+
+// renderCadenceTemplate renders a feed's Cadence transaction template
+// with the live contract address.
+func renderCadenceTemplate(tmpl, symbol string) (string, error) {
+	parsed, err := template.New("cadence").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := parsed.Execute(&buf, cadenceTemplateData{
+		ContractAddress: contractAddress,
+		Symbol:          symbol,
+	}); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import "testing"
+
+func TestValidateCadenceTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmpl    string
+		wantErr bool
+	}{
+		{
+			name: "valid template renders",
+			tmpl: `import PriceOracle from {{.ContractAddress}}
+transaction(newPrice: UFix64) {}`,
+		},
+		{
+			name:    "empty template",
+			tmpl:    "   ",
+			wantErr: true,
+		},
+		{
+			name:    "malformed template syntax",
+			tmpl:    `{{.ContractAddress`,
+			wantErr: true,
+		},
+		{
+			name:    "references an undeclared field",
+			tmpl:    `{{.Nonexistent}}`,
+			wantErr: true,
+		},
+		{
+			name: "references symbol field",
+			tmpl: `transaction for {{.Symbol}} from {{.ContractAddress}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCadenceTemplate(tt.tmpl)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCadenceTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewFeedDefaultsDecimals(t *testing.T) {
+	feed, err := newFeed(FeedConfig{
+		Symbol:                     "BTC",
+		CadenceTransactionTemplate: defaultFLOWCadenceTemplate,
+		DeviationThreshold:         0.01,
+		Heartbeat:                  "1h",
+	})
+	if err != nil {
+		t.Fatalf("newFeed() error = %v", err)
+	}
+	if feed.Decimals != 8 {
+		t.Errorf("Decimals = %d, want default of 8", feed.Decimals)
+	}
+}
+
+func TestNewFeedKeepsExplicitDecimals(t *testing.T) {
+	feed, err := newFeed(FeedConfig{
+		Symbol:                     "USDC",
+		CadenceTransactionTemplate: defaultFLOWCadenceTemplate,
+		DeviationThreshold:         0.01,
+		Heartbeat:                  "1h",
+		Decimals:                   6,
+	})
+	if err != nil {
+		t.Fatalf("newFeed() error = %v", err)
+	}
+	if feed.Decimals != 6 {
+		t.Errorf("Decimals = %d, want 6", feed.Decimals)
+	}
+}
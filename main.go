@@ -2,12 +2,11 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
+	"math"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -25,26 +24,23 @@ import (
 const (
 	flowAccessNode = "access.testnet.nodes.onflow.org:9000"
 	updateInterval = 5 * time.Minute
-	coinGeckoAPI   = "https://api.coingecko.com/api/v3/simple/price?ids=flow&vs_currencies=usd"
+	coinGeckoAPI   = "https://api.coingecko.com/api/v3/simple/price?ids=flow&vs_currencies=usd&include_last_updated_at=true"
 )
 
 var contractAddress string
 
-type PriceResponse struct {
-	Flow struct {
-		USD float64 `json:"usd"`
-	} `json:"flow"`
-}
-
 type OracleUpdater struct {
 	flowClient *client.Client
 	account    *flow.Account
 	privateKey crypto.PrivateKey
 	signer     crypto.Signer
 	db         *pgxpool.Pool
+	policy     *UpdatePolicy
+	registry   *FeedRegistry
+	cluster    *ClusterNode
 }
 
-func NewOracleUpdater(privateKeyHex string, accountAddress string, databaseURL string) (*OracleUpdater, error) {
+func NewOracleUpdater(privateKeyHex string, accountAddress string, databaseURL string, registry *FeedRegistry) (*OracleUpdater, error) {
 	flowClient, err := client.New(flowAccessNode, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Flow: %w", err)
@@ -107,56 +103,110 @@ func NewOracleUpdater(privateKeyHex string, accountAddress string, databaseURL s
 		privateKey: privateKey,
 		signer:     signer,
 		db:         db,
+		policy:     NewUpdatePolicy(db),
+		registry:   registry,
 	}, nil
 }
 
-func (o *OracleUpdater) GetFlowPrice() (float64, error) {
-	resp, err := http.Get(coinGeckoAPI)
+// GetPrice fans out to every PriceSource configured for feed in parallel,
+// rejects stale and outlier quotes, and returns the volume-weighted
+// median of the rest. The raw per-source quotes and the derived
+// aggregate are persisted so operators can audit which feeds influenced
+// a given on-chain write.
+func (o *OracleUpdater) GetPrice(feed *Feed) (float64, error) {
+	ctx := context.Background()
+
+	result, err := feed.aggregator.Aggregate(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to fetch price: %w", err)
+		return 0, fmt.Errorf("failed to aggregate price for %s: %w", feed.Symbol, err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return 0, fmt.Errorf("failed to read response: %w", err)
+	if len(result.Dropped) > 0 {
+		log.Printf("⚠️  Dropped %d source(s) during %s aggregation: %v", len(result.Dropped), feed.Symbol, result.Dropped)
 	}
 
-	var priceResp PriceResponse
-	if err := json.Unmarshal(body, &priceResp); err != nil {
-		return 0, fmt.Errorf("failed to parse price: %w", err)
+	if err := o.SavePriceSources(feed.Symbol, result); err != nil {
+		log.Printf("❌ Error saving price sources for %s: %v", feed.Symbol, err)
 	}
 
-	return priceResp.Flow.USD, nil
+	return result.Price, nil
 }
 
-var lastTxID string
-
-func (o *OracleUpdater) UpdatePriceOnChain(price float64) error {
+// SavePriceSources persists each per-source quote along with the derived
+// aggregate price so a given on-chain write can be audited back to the
+// feeds that influenced it.
+func (o *OracleUpdater) SavePriceSources(symbol string, result *AggregateResult) error {
 	ctx := context.Background()
 
-	script := fmt.Sprintf(`
-import PriceOracle from %s
-
-transaction(newPrice: UFix64) {
-    prepare(signer: auth(Storage) &Account) {
-        let admin = signer.storage.borrow<&PriceOracle.Admin>(
-            from: PriceOracle.AdminStoragePath
-        ) ?? panic("Could not borrow admin resource")
-        
-        admin.updateFlowPrice(newPrice: newPrice)
-    }
+	aggregateID := uuid.New().String()
+
+	for _, q := range result.Quotes {
+		query := `
+			INSERT INTO price_sources (id, aggregate_id, symbol, source, price_usd, volume, aggregate_price_usd, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`
+		_, err := o.db.Exec(ctx, query,
+			uuid.New().String(),
+			aggregateID,
+			symbol,
+			q.Source,
+			decimal.NewFromFloat(q.Price),
+			decimal.NewFromFloat(q.Volume),
+			decimal.NewFromFloat(result.Price),
+			q.Timestamp,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert price source %s: %w", q.Source, err)
+		}
+	}
+
+	return nil
+}
+
+var (
+	gasLimitMu    sync.Mutex
+	lastGasLimits = map[string]uint64{}
+)
+
+// recordGasLimit tracks the gas limit submitted with the most recent
+// on-chain write per symbol, for the /metrics gas gauge. Flow's access
+// API does not report actual gas consumption back to a lightweight
+// client, so this reflects the configured limit rather than execution
+// cost.
+func recordGasLimit(symbol string, limit uint64) {
+	gasLimitMu.Lock()
+	defer gasLimitMu.Unlock()
+	lastGasLimits[symbol] = limit
 }
-`, contractAddress)
+
+func getLastGasLimit(symbol string) (uint64, bool) {
+	gasLimitMu.Lock()
+	defer gasLimitMu.Unlock()
+	limit, ok := lastGasLimits[symbol]
+	return limit, ok
+}
+
+// UpdatePriceOnChain renders feed's Cadence transaction template and
+// submits it with price as the sole UFix64 argument. It returns the
+// submitted transaction's ID so callers can attribute it to the right
+// feed without relying on shared state (runFeed runs one goroutine per
+// feed, so multiple UpdatePriceOnChain calls can be in flight at once).
+func (o *OracleUpdater) UpdatePriceOnChain(feed *Feed, price float64) (string, error) {
+	ctx := context.Background()
+
+	script, err := renderCadenceTemplate(feed.CadenceTransactionTemplate, feed.Symbol)
+	if err != nil {
+		return "", fmt.Errorf("failed to render cadence template for %s: %w", feed.Symbol, err)
+	}
 
 	account, err := o.flowClient.GetAccount(ctx, o.account.Address)
 	if err != nil {
-		return fmt.Errorf("failed to get account: %w", err)
+		return "", fmt.Errorf("failed to get account: %w", err)
 	}
 
 	latestBlock, err := o.flowClient.GetLatestBlock(ctx, true)
 	if err != nil {
-		return fmt.Errorf("failed to get latest block: %w", err)
+		return "", fmt.Errorf("failed to get latest block: %w", err)
 	}
 
 	tx := flow.NewTransaction().
@@ -167,85 +217,128 @@ transaction(newPrice: UFix64) {
 		SetPayer(o.account.Address).
 		AddAuthorizer(o.account.Address)
 
-	priceArg, err := CadenceUFix64(price)
+	priceArg, err := CadenceUFix64(price, feed.Decimals)
 	if err != nil {
-		return fmt.Errorf("failed to convert price: %w", err)
+		return "", fmt.Errorf("failed to convert price: %w", err)
 	}
 	if err := tx.AddArgument(priceArg); err != nil {
-		return fmt.Errorf("failed to add argument: %w", err)
+		return "", fmt.Errorf("failed to add argument: %w", err)
 	}
 
 	if err := tx.SignEnvelope(o.account.Address, o.account.Keys[0].Index, o.signer); err != nil {
-		return fmt.Errorf("failed to sign transaction: %w", err)
+		return "", fmt.Errorf("failed to sign transaction: %w", err)
 	}
 
 	if err := o.flowClient.SendTransaction(ctx, *tx); err != nil {
-		return fmt.Errorf("failed to send transaction: %w", err)
+		return "", fmt.Errorf("failed to send transaction: %w", err)
 	}
 
 	result, err := waitForSeal(ctx, o.flowClient, tx.ID())
 	if err != nil {
-		return fmt.Errorf("failed to wait for seal: %w", err)
+		return "", fmt.Errorf("failed to wait for seal: %w", err)
 	}
 
 	if result.Error != nil {
-		return fmt.Errorf("transaction failed: %v", result.Error)
+		return "", fmt.Errorf("transaction failed: %v", result.Error)
 	}
 
-	lastTxID = tx.ID().String()
-	log.Printf("✅ Price updated to $%.4f (TX: %s)", price, tx.ID())
-	return nil
+	recordGasLimit(feed.Symbol, 100)
+	log.Printf("✅ %s price updated to $%.4f (TX: %s)", feed.Symbol, price, tx.ID())
+	return tx.ID().String(), nil
 }
 
+// Run starts one independent ticker loop per feed in the registry, plus
+// the PriceRequested event subscriber, and blocks until the process
+// exits.
 func (o *OracleUpdater) Run() {
-	ticker := time.NewTicker(updateInterval)
-	defer ticker.Stop()
-
-	log.Printf("🚀 Starting FLOW price oracle updater")
-	log.Printf("📊 Update interval: %v", updateInterval)
+	log.Printf("🚀 Starting price oracle updater")
+	log.Printf("📊 Feeds configured: %d", len(o.registry.Feeds))
 	log.Printf("📍 Contract address: %s", contractAddress)
 	log.Printf("🌐 Network: Flow Testnet\n")
 
-	o.updatePrice()
+	subscriber := NewPriceRequestSubscriber(o)
+	go subscriber.Run(context.Background())
+
+	for _, feed := range o.registry.Feeds {
+		go o.runFeed(feed)
+	}
+	select {}
+}
+
+// runFeed ticks a single feed on updateInterval for the lifetime of the
+// process, fetching and conditionally posting its price independently of
+// every other feed.
+func (o *OracleUpdater) runFeed(feed *Feed) {
+	log.Printf("📊 %s: starting feed (heartbeat %v, deviation threshold %.4f%%)", feed.Symbol, feed.heartbeat, feed.DeviationThreshold*100)
+
+	ticker := time.NewTicker(updateInterval)
+	defer ticker.Stop()
+
+	o.updatePrice(feed, false)
 
 	for range ticker.C {
-		o.updatePrice()
+		o.updatePrice(feed, false)
 	}
 }
 
-func (o *OracleUpdater) updatePrice() {
+// updatePrice fetches feed's current aggregated price and, unless force
+// is set, consults the UpdatePolicy before deciding whether it's worth
+// the cost of an on-chain write. force bypasses that gate entirely, for
+// manual triggers that must land on-chain regardless of drift or
+// heartbeat state.
+func (o *OracleUpdater) updatePrice(feed *Feed, force bool) {
 
-	price, err := o.GetFlowPrice()
+	price, err := o.GetPrice(feed)
 	if err != nil {
-		log.Printf("❌ Error fetching price: %v", err)
+		log.Printf("❌ %s: error fetching price: %v", feed.Symbol, err)
 		return
 	}
 
-	log.Printf("💰 Fetched FLOW price: $%.4f", price)
+	log.Printf("💰 Fetched %s price: $%.4f", feed.Symbol, price)
 
 	txID := ""
 
 	skipBlockchain := os.Getenv("SKIP_BLOCKCHAIN") == "true"
 
-	if !skipBlockchain {
-		if err := o.UpdatePriceOnChain(price); err != nil {
-			log.Printf("❌ Error updating price on-chain: %v", err)
+	shouldUpdate, reason := true, "manual trigger bypassing update policy"
+	if !force {
+		shouldUpdate, reason, err = o.policy.ShouldUpdate(context.Background(), feed.Symbol, price, feed.DeviationThreshold, feed.heartbeat)
+		if err != nil {
+			log.Printf("❌ %s: error evaluating update policy: %v", feed.Symbol, err)
+			shouldUpdate = true
+			reason = "falling back to update after policy error"
+		}
+	}
+
+	if !skipBlockchain && shouldUpdate && o.cluster != nil {
+		txID = o.updatePriceViaCluster(feed, price)
+	} else if !skipBlockchain && shouldUpdate {
+		log.Printf("📤 %s: posting on-chain update: %s", feed.Symbol, reason)
+		chainTxID, err := o.UpdatePriceOnChain(feed, price)
+		if err != nil {
+			log.Printf("❌ %s: error updating price on-chain: %v", feed.Symbol, err)
 			log.Printf("⚠️  Continuing with database update only...")
 			txID = "skipped_" + fmt.Sprintf("%d", time.Now().Unix())
 		} else {
-
-			txID = o.getLastTxID()
+			txID = chainTxID
+			if err := o.policy.RecordOnChainUpdate(context.Background(), feed.Symbol, price, time.Now()); err != nil {
+				log.Printf("❌ %s: error recording on-chain update state: %v", feed.Symbol, err)
+			}
 		}
-	} else {
+	} else if skipBlockchain {
 		log.Printf("⚠️  Skipping blockchain update (SKIP_BLOCKCHAIN=true)")
 		txID = "local_" + fmt.Sprintf("%d", time.Now().Unix())
+	} else {
+		log.Printf("⏭️  %s: skipping on-chain update: %s", feed.Symbol, reason)
+		txID = "skipped_" + fmt.Sprintf("%d", time.Now().Unix())
 	}
 
-	if err := o.SavePriceToDatabase(price, txID); err != nil {
+	priceOracleID, err := o.SavePriceToDatabase(feed.Symbol, price, txID)
+	if err != nil {
 		log.Printf("❌ Error saving price to database: %v", err)
-	} else {
+	} else if feed.Symbol == "FLOW" {
 
-		if err := o.UpdateProtocolAPYs(price); err != nil {
+		if err := o.UpdateProtocolAPYs(price, priceOracleID); err != nil {
 			log.Printf("❌ Error updating protocol APYs: %v", err)
 		}
 	}
@@ -266,41 +359,42 @@ func waitForSeal(ctx context.Context, c *client.Client, txID flow.Identifier) (*
 	}
 }
 
-func CadenceUFix64(value float64) (cadence.Value, error) {
-
-	intValue := uint64(value * 100000000)
-	return cadence.NewUFix64(fmt.Sprintf("%d.%08d", intValue/100000000, intValue%100000000))
-}
+// CadenceUFix64 converts value into a Cadence UFix64 literal, rounding to
+// decimals fractional digits so a feed declaring fewer decimals than the
+// default 8 (e.g. a token priced to 6 places) isn't posted on-chain at the
+// wrong scale.
+func CadenceUFix64(value float64, decimals int) (cadence.Value, error) {
+	scale := uint64(math.Pow10(decimals))
 
-func (o *OracleUpdater) getLastTxID() string {
-	return lastTxID
+	intValue := uint64(math.Round(value * float64(scale)))
+	return cadence.NewUFix64(fmt.Sprintf("%d.%0*d", intValue/scale, decimals, intValue%scale))
 }
 
-var lastPriceOracleID string
-
-func (o *OracleUpdater) SavePriceToDatabase(price float64, txHash string) error {
+// SavePriceToDatabase inserts a price_oracle row and returns its ID so
+// callers (e.g. UpdateProtocolAPYs) can reference it directly instead of
+// through shared state that concurrent per-feed goroutines would race on.
+func (o *OracleUpdater) SavePriceToDatabase(symbol string, price float64, txHash string) (string, error) {
 	ctx := context.Background()
 
 	priceDecimal := decimal.NewFromFloat(price)
 
 	id := uuid.New().String()
-	lastPriceOracleID = id
 
 	query := `
 		INSERT INTO price_oracle (id, symbol, price_usd, tx_hash, created_at)
 		VALUES ($1, $2, $3, $4, $5)
 	`
 
-	_, err := o.db.Exec(ctx, query, id, "FLOW", priceDecimal, txHash, time.Now())
+	_, err := o.db.Exec(ctx, query, id, symbol, priceDecimal, txHash, time.Now())
 	if err != nil {
-		return fmt.Errorf("failed to insert price into database: %w", err)
+		return "", fmt.Errorf("failed to insert price into database: %w", err)
 	}
 
-	log.Printf("💾 Price saved to database (ID: %s)", id)
-	return nil
+	log.Printf("💾 %s price saved to database (ID: %s)", symbol, id)
+	return id, nil
 }
 
-func (o *OracleUpdater) UpdateProtocolAPYs(flowPrice float64) error {
+func (o *OracleUpdater) UpdateProtocolAPYs(flowPrice float64, priceOracleID string) error {
 	ctx := context.Background()
 
 	baseRates := map[string]float64{
@@ -332,7 +426,7 @@ func (o *OracleUpdater) UpdateProtocolAPYs(flowPrice float64) error {
 			protocol,
 			decimal.NewFromFloat(adjustedAPY),
 			decimal.NewFromFloat(flowPrice),
-			lastPriceOracleID,
+			priceOracleID,
 			time.Now(),
 		)
 
@@ -347,8 +441,60 @@ func (o *OracleUpdater) UpdateProtocolAPYs(flowPrice float64) error {
 	return nil
 }
 
+// updatePriceViaCluster gossips this node's signed observation of price
+// to its cluster peers and, once a threshold of signatures is gathered,
+// lets only the elected Raft leader submit the on-chain transaction with
+// the signature bundle attached. Followers and rounds that fail to reach
+// quorum skip the on-chain write for this tick but still record the
+// attempt locally.
+func (o *OracleUpdater) updatePriceViaCluster(feed *Feed, price float64) string {
+	ctx := context.Background()
+
+	bundle, agreedPrice, ok, err := o.cluster.ProposeRound(ctx, feed.Symbol, price)
+	if err != nil {
+		log.Printf("❌ %s: error proposing cluster round: %v", feed.Symbol, err)
+		return "skipped_" + fmt.Sprintf("%d", time.Now().Unix())
+	}
+	if !ok {
+		log.Printf("⏭️  %s: cluster round failed to reach quorum, skipping on-chain write", feed.Symbol)
+		return "skipped_" + fmt.Sprintf("%d", time.Now().Unix())
+	}
+	if !o.cluster.IsLeader() {
+		log.Printf("🤝 %s: quorum reached, deferring on-chain write to cluster leader", feed.Symbol)
+		return "follower_" + fmt.Sprintf("%d", time.Now().Unix())
+	}
+
+	// Post the round's agreed value, not this node's own raw reading: the
+	// bundle's signatures were collected over agreedPrice in the confirm
+	// phase, and updatePriceWithAttestations verifies each one against
+	// whatever price accompanies it.
+	txID, err := o.submitThresholdUpdate(feed, agreedPrice, bundle)
+	if err != nil {
+		log.Printf("❌ %s: error submitting threshold update: %v", feed.Symbol, err)
+		return "skipped_" + fmt.Sprintf("%d", time.Now().Unix())
+	}
+
+	if err := o.policy.RecordOnChainUpdate(ctx, feed.Symbol, agreedPrice, time.Now()); err != nil {
+		log.Printf("❌ %s: error recording on-chain update state: %v", feed.Symbol, err)
+	}
+
+	return txID
+}
+
+// SetCluster enables threshold-signed multi-node mode: on-chain updates
+// are then gated on reaching consensus with node's cluster peers rather
+// than decided unilaterally.
+func (o *OracleUpdater) SetCluster(node *ClusterNode) {
+	o.cluster = node
+}
+
 func (o *OracleUpdater) Close() error {
 	o.db.Close()
+	if o.cluster != nil {
+		if err := o.cluster.Close(); err != nil {
+			log.Printf("⚠️  Error closing cluster node: %v", err)
+		}
+	}
 	return o.flowClient.Close()
 }
 
@@ -378,11 +524,34 @@ func main() {
 		log.Fatal("DATABASE_URL environment variable is required")
 	}
 
-	updater, err := NewOracleUpdater(privateKey, accountAddress, databaseURL)
+	registry, err := loadFeedRegistry()
+	if err != nil {
+		log.Fatalf("Failed to load feed registry: %v", err)
+	}
+
+	updater, err := NewOracleUpdater(privateKey, accountAddress, databaseURL, registry)
 	if err != nil {
 		log.Fatalf("Failed to create oracle updater: %v", err)
 	}
 	defer updater.Close()
 
+	if clusterConfig, ok, err := loadClusterConfigFromEnv(); err != nil {
+		log.Fatalf("Failed to load cluster config: %v", err)
+	} else if ok {
+		clusterNode, err := NewClusterNode(context.Background(), clusterConfig, updater.db)
+		if err != nil {
+			log.Fatalf("Failed to start cluster node: %v", err)
+		}
+		log.Printf("🧩 Cluster mode enabled (node %s, %d peer(s))", clusterConfig.NodeID, len(clusterConfig.Peers))
+		updater.SetCluster(clusterNode)
+	}
+
+	apiServer := NewAPIServer(updater)
+	go func() {
+		if err := apiServer.Start(context.Background()); err != nil {
+			log.Printf("❌ API server stopped: %v", err)
+		}
+	}()
+
 	updater.Run()
 }
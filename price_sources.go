@@ -0,0 +1,415 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	sourceTimeout           = 3 * time.Second
+	maxQuoteAge             = 30 * time.Second
+	defaultOutlierThreshold = 0.05 // 5%
+)
+
+// defaultOutlierThresholdFromEnv lets operators override the package
+// default used when a feed's configuration doesn't specify its own
+// outlier threshold.
+func defaultOutlierThresholdFromEnv() float64 {
+	v := os.Getenv("OUTLIER_THRESHOLD")
+	if v == "" {
+		return defaultOutlierThreshold
+	}
+	parsed, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Printf("⚠️  Invalid OUTLIER_THRESHOLD %q, using default %.4f", v, defaultOutlierThreshold)
+		return defaultOutlierThreshold
+	}
+	return parsed
+}
+
+// Quote is a single price observation returned by a PriceSource.
+type Quote struct {
+	Source    string
+	Price     float64
+	Volume    float64
+	Timestamp time.Time
+}
+
+// PriceSource fetches a FLOW/USD quote from a single upstream feed.
+type PriceSource interface {
+	Name() string
+	FetchFlowPrice(ctx context.Context) (Quote, error)
+}
+
+func defaultPriceSources() []PriceSource {
+	return []PriceSource{
+		&coinGeckoSource{},
+		&coinbaseSource{},
+		&binanceSource{},
+		&krakenSource{},
+		&kucoinSource{},
+	}
+}
+
+type coinGeckoSource struct{}
+
+func (s *coinGeckoSource) Name() string { return "coingecko" }
+
+func (s *coinGeckoSource) FetchFlowPrice(ctx context.Context) (Quote, error) {
+	var body struct {
+		Flow struct {
+			USD           float64 `json:"usd"`
+			LastUpdatedAt int64   `json:"last_updated_at"`
+		} `json:"flow"`
+	}
+	if err := fetchJSON(ctx, coinGeckoAPI, &body); err != nil {
+		return Quote{}, err
+	}
+	return Quote{Source: s.Name(), Price: body.Flow.USD, Timestamp: time.Unix(body.Flow.LastUpdatedAt, 0)}, nil
+}
+
+type coinbaseSource struct{}
+
+func (s *coinbaseSource) Name() string { return "coinbase" }
+
+func (s *coinbaseSource) FetchFlowPrice(ctx context.Context) (Quote, error) {
+	var body struct {
+		Data struct {
+			Amount string `json:"amount"`
+		} `json:"data"`
+	}
+	if err := fetchJSON(ctx, "https://api.coinbase.com/v2/prices/FLOW-USD/spot", &body); err != nil {
+		return Quote{}, err
+	}
+	price, err := decimal.NewFromString(body.Data.Amount)
+	if err != nil {
+		return Quote{}, fmt.Errorf("coinbase: failed to parse price: %w", err)
+	}
+	f, _ := price.Float64()
+	// The spot price endpoint reports no trade or quote time, so the
+	// fetch time is the best available proxy for staleness checks.
+	return Quote{Source: s.Name(), Price: f, Timestamp: time.Now()}, nil
+}
+
+type binanceSource struct{}
+
+func (s *binanceSource) Name() string { return "binance" }
+
+func (s *binanceSource) FetchFlowPrice(ctx context.Context) (Quote, error) {
+	var body struct {
+		LastPrice string `json:"lastPrice"`
+		Volume    string `json:"volume"`
+		CloseTime int64  `json:"closeTime"`
+	}
+	if err := fetchJSON(ctx, "https://api.binance.com/api/v3/ticker/24hr?symbol=FLOWUSDT", &body); err != nil {
+		return Quote{}, err
+	}
+	price, err := decimal.NewFromString(body.LastPrice)
+	if err != nil {
+		return Quote{}, fmt.Errorf("binance: failed to parse price: %w", err)
+	}
+	volume, err := decimal.NewFromString(body.Volume)
+	if err != nil {
+		return Quote{}, fmt.Errorf("binance: failed to parse volume: %w", err)
+	}
+	f, _ := price.Float64()
+	v, _ := volume.Float64()
+	return Quote{Source: s.Name(), Price: f, Volume: v, Timestamp: time.UnixMilli(body.CloseTime)}, nil
+}
+
+type krakenSource struct{}
+
+func (s *krakenSource) Name() string { return "kraken" }
+
+func (s *krakenSource) FetchFlowPrice(ctx context.Context) (Quote, error) {
+	var body struct {
+		Error  []string `json:"error"`
+		Result map[string]struct {
+			C []string `json:"c"` // last trade: [price, lot volume]
+			V []string `json:"v"` // volume: [today, last 24 hours]
+		} `json:"result"`
+	}
+	if err := fetchJSON(ctx, "https://api.kraken.com/0/public/Ticker?pair=FLOWUSD", &body); err != nil {
+		return Quote{}, err
+	}
+	if len(body.Error) > 0 {
+		return Quote{}, fmt.Errorf("kraken: %v", body.Error)
+	}
+	for _, ticker := range body.Result {
+		if len(ticker.C) == 0 {
+			continue
+		}
+		price, err := decimal.NewFromString(ticker.C[0])
+		if err != nil {
+			return Quote{}, fmt.Errorf("kraken: failed to parse price: %w", err)
+		}
+		f, _ := price.Float64()
+
+		var v float64
+		if len(ticker.V) == 2 {
+			if volume, err := decimal.NewFromString(ticker.V[1]); err == nil {
+				v, _ = volume.Float64()
+			}
+		}
+
+		// Ticker carries no trade or quote timestamp, so fetch time is
+		// the best available proxy for staleness checks.
+		return Quote{Source: s.Name(), Price: f, Volume: v, Timestamp: time.Now()}, nil
+	}
+	return Quote{}, fmt.Errorf("kraken: no ticker in response")
+}
+
+type kucoinSource struct{}
+
+func (s *kucoinSource) Name() string { return "kucoin" }
+
+func (s *kucoinSource) FetchFlowPrice(ctx context.Context) (Quote, error) {
+	var body struct {
+		Data struct {
+			Price string `json:"price"`
+		} `json:"data"`
+	}
+	if err := fetchJSON(ctx, "https://api.kucoin.com/api/1/prices?symbols=FLOW-USDT", &body); err != nil {
+		return Quote{}, err
+	}
+	price, err := decimal.NewFromString(body.Data.Price)
+	if err != nil {
+		return Quote{}, fmt.Errorf("kucoin: failed to parse price: %w", err)
+	}
+	f, _ := price.Float64()
+	// This endpoint reports no trade or quote time, so fetch time is the
+	// best available proxy for staleness checks.
+	return Quote{Source: s.Name(), Price: f, Timestamp: time.Now()}, nil
+}
+
+func fetchJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return nil
+}
+
+// SourceStat summarizes one PriceSource's recent health, as surfaced by
+// the GET /sources API endpoint.
+type SourceStat struct {
+	LastQuote    float64
+	LastQuoteAt  time.Time
+	LastError    string
+	SuccessCount int
+	ErrorCount   int
+}
+
+// PriceAggregator fans out to a set of PriceSource feeds and combines their
+// quotes into a single robust FLOW/USD price.
+type PriceAggregator struct {
+	sources          []PriceSource
+	outlierThreshold float64
+
+	mu    sync.Mutex
+	stats map[string]*SourceStat
+}
+
+func NewPriceAggregator(sources []PriceSource, outlierThreshold float64) *PriceAggregator {
+	return &PriceAggregator{sources: sources, outlierThreshold: outlierThreshold, stats: map[string]*SourceStat{}}
+}
+
+// Stats returns a snapshot of each source's recent success/error counts
+// and last observed quote, for the GET /sources API endpoint.
+func (a *PriceAggregator) Stats() map[string]SourceStat {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	snapshot := make(map[string]SourceStat, len(a.stats))
+	for name, stat := range a.stats {
+		snapshot[name] = *stat
+	}
+	return snapshot
+}
+
+func (a *PriceAggregator) recordSuccess(name string, price float64, at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stat := a.statFor(name)
+	stat.SuccessCount++
+	stat.LastQuote = price
+	stat.LastQuoteAt = at
+}
+
+func (a *PriceAggregator) recordError(name, errMsg string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stat := a.statFor(name)
+	stat.ErrorCount++
+	stat.LastError = errMsg
+}
+
+func (a *PriceAggregator) statFor(name string) *SourceStat {
+	stat, ok := a.stats[name]
+	if !ok {
+		stat = &SourceStat{}
+		a.stats[name] = stat
+	}
+	return stat
+}
+
+// AggregateResult is the outcome of one aggregation round, including the
+// raw per-source quotes so callers can persist them for auditing.
+type AggregateResult struct {
+	Price   float64
+	Quotes  []Quote
+	Dropped []string
+}
+
+// Aggregate fetches quotes from all sources in parallel, drops stale or
+// errored sources and deviation outliers, and returns the volume-weighted
+// median of what remains.
+func (a *PriceAggregator) Aggregate(ctx context.Context) (*AggregateResult, error) {
+	type fetchResult struct {
+		name  string
+		quote Quote
+		err   error
+	}
+
+	results := make(chan fetchResult, len(a.sources))
+
+	for _, src := range a.sources {
+		go func(src PriceSource) {
+			sctx, cancel := context.WithTimeout(ctx, sourceTimeout)
+			defer cancel()
+
+			quote, err := src.FetchFlowPrice(sctx)
+			if err != nil {
+				results <- fetchResult{name: src.Name(), err: fmt.Errorf("%s: %w", src.Name(), err)}
+				return
+			}
+			results <- fetchResult{name: src.Name(), quote: quote}
+		}(src)
+	}
+
+	var quotes []Quote
+	var dropped []string
+
+	for i := 0; i < len(a.sources); i++ {
+		res := <-results
+		if res.err != nil {
+			dropped = append(dropped, res.err.Error())
+			a.recordError(res.name, res.err.Error())
+			continue
+		}
+		if time.Since(res.quote.Timestamp) > maxQuoteAge {
+			dropped = append(dropped, fmt.Sprintf("%s: stale quote", res.quote.Source))
+			a.recordError(res.name, "stale quote")
+			continue
+		}
+		a.recordSuccess(res.name, res.quote.Price, res.quote.Timestamp)
+		quotes = append(quotes, res.quote)
+	}
+
+	if len(quotes) == 0 {
+		return nil, fmt.Errorf("no price sources returned a usable quote")
+	}
+
+	median := medianPrice(quotes)
+
+	var kept []Quote
+	for _, q := range quotes {
+		deviation := (q.Price - median) / median
+		if deviation < 0 {
+			deviation = -deviation
+		}
+		if deviation > a.outlierThreshold {
+			dropped = append(dropped, fmt.Sprintf("%s: deviation %.2f%% exceeds threshold", q.Source, deviation*100))
+			continue
+		}
+		kept = append(kept, q)
+	}
+
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("all sources rejected as outliers around median %.4f", median)
+	}
+
+	return &AggregateResult{
+		Price:   weightedMedian(kept),
+		Quotes:  quotes,
+		Dropped: dropped,
+	}, nil
+}
+
+func medianPrice(quotes []Quote) float64 {
+	prices := make([]float64, len(quotes))
+	for i, q := range quotes {
+		prices[i] = q.Price
+	}
+	sort.Float64s(prices)
+
+	mid := len(prices) / 2
+	if len(prices)%2 == 0 {
+		return (prices[mid-1] + prices[mid]) / 2
+	}
+	return prices[mid]
+}
+
+// weightedMedian returns the volume-weighted median price: sources without a
+// reported volume fall back to an equal weight of 1. When the cumulative
+// weight lands exactly on half, the two prices straddling it are averaged,
+// so equal weights give the plain median.
+func weightedMedian(quotes []Quote) float64 {
+	sorted := make([]Quote, len(quotes))
+	copy(sorted, quotes)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Price < sorted[j].Price })
+
+	var totalWeight float64
+	for _, q := range sorted {
+		totalWeight += quoteWeight(q)
+	}
+
+	var cumulative float64
+	for i, q := range sorted {
+		cumulative += quoteWeight(q)
+		if cumulative == totalWeight/2 && i+1 < len(sorted) {
+			return (q.Price + sorted[i+1].Price) / 2
+		}
+		if cumulative >= totalWeight/2 {
+			return q.Price
+		}
+	}
+
+	return sorted[len(sorted)-1].Price
+}
+
+func quoteWeight(q Quote) float64 {
+	if q.Volume > 0 {
+		return q.Volume
+	}
+	return 1
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMedianPrice(t *testing.T) {
+	tests := []struct {
+		name   string
+		prices []float64
+		want   float64
+	}{
+		{"single", []float64{1.5}, 1.5},
+		{"odd", []float64{3, 1, 2}, 2},
+		{"even", []float64{1, 2, 3, 4}, 2.5},
+		{"unsorted duplicates", []float64{5, 1, 5, 1, 3}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quotes := make([]Quote, len(tt.prices))
+			for i, p := range tt.prices {
+				quotes[i] = Quote{Price: p}
+			}
+			if got := medianPrice(quotes); got != tt.want {
+				t.Errorf("medianPrice(%v) = %v, want %v", tt.prices, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeightedMedian(t *testing.T) {
+	tests := []struct {
+		name   string
+		quotes []Quote
+		want   float64
+	}{
+		{
+			name: "equal weight falls back to plain median",
+			quotes: []Quote{
+				{Price: 1},
+				{Price: 2},
+				{Price: 3},
+			},
+			want: 2,
+		},
+		{
+			name: "heavy volume pulls the result toward it",
+			quotes: []Quote{
+				{Price: 1, Volume: 1},
+				{Price: 2, Volume: 1},
+				{Price: 3, Volume: 100},
+			},
+			want: 3,
+		},
+		{
+			name: "mixed volume and default weight",
+			quotes: []Quote{
+				{Price: 1, Volume: 0},
+				{Price: 2, Volume: 10},
+				{Price: 3, Volume: 0},
+			},
+			want: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := weightedMedian(tt.quotes); got != tt.want {
+				t.Errorf("weightedMedian() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuoteWeightDefaultsToOne(t *testing.T) {
+	if w := quoteWeight(Quote{Price: 1}); w != 1 {
+		t.Errorf("quoteWeight() with no volume = %v, want 1", w)
+	}
+	if w := quoteWeight(Quote{Price: 1, Volume: 42}); w != 42 {
+		t.Errorf("quoteWeight() with volume = %v, want 42", w)
+	}
+}
+
+// fakeSource is a PriceSource with a canned quote or error, used to test
+// PriceAggregator without making real network calls.
+type fakeSource struct {
+	name  string
+	quote Quote
+	err   error
+}
+
+func (f *fakeSource) Name() string { return f.name }
+
+func (f *fakeSource) FetchFlowPrice(ctx context.Context) (Quote, error) {
+	if f.err != nil {
+		return Quote{}, f.err
+	}
+	return f.quote, nil
+}
+
+func TestAggregateDropsDeviationOutliers(t *testing.T) {
+	now := time.Now()
+	sources := []PriceSource{
+		&fakeSource{name: "a", quote: Quote{Source: "a", Price: 1.00, Timestamp: now}},
+		&fakeSource{name: "b", quote: Quote{Source: "b", Price: 1.01, Timestamp: now}},
+		&fakeSource{name: "c", quote: Quote{Source: "c", Price: 1.50, Timestamp: now}}, // 50% off median
+	}
+
+	agg := NewPriceAggregator(sources, defaultOutlierThreshold)
+	result, err := agg.Aggregate(context.Background())
+	if err != nil {
+		t.Fatalf("Aggregate() error = %v", err)
+	}
+
+	if len(result.Dropped) != 1 {
+		t.Fatalf("expected 1 dropped source, got %d: %v", len(result.Dropped), result.Dropped)
+	}
+	if result.Price != 1.005 {
+		t.Errorf("Price = %v, want 1.005 (median of a, b only)", result.Price)
+	}
+}
+
+func TestAggregateDropsStaleQuotes(t *testing.T) {
+	sources := []PriceSource{
+		&fakeSource{name: "fresh", quote: Quote{Source: "fresh", Price: 1.0, Timestamp: time.Now()}},
+		&fakeSource{name: "stale", quote: Quote{Source: "stale", Price: 1.0, Timestamp: time.Now().Add(-time.Hour)}},
+	}
+
+	agg := NewPriceAggregator(sources, defaultOutlierThreshold)
+	result, err := agg.Aggregate(context.Background())
+	if err != nil {
+		t.Fatalf("Aggregate() error = %v", err)
+	}
+
+	if len(result.Quotes) != 1 || result.Quotes[0].Source != "fresh" {
+		t.Errorf("expected only the fresh quote to survive, got %v", result.Quotes)
+	}
+}
+
+func TestAggregateErrorsWhenNoSourceSucceeds(t *testing.T) {
+	sources := []PriceSource{
+		&fakeSource{name: "a", err: fmt.Errorf("boom")},
+	}
+
+	agg := NewPriceAggregator(sources, defaultOutlierThreshold)
+	if _, err := agg.Aggregate(context.Background()); err == nil {
+		t.Fatal("expected an error when every source fails")
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	defaultDeviationThreshold = 0.005 // 0.5%
+	defaultHeartbeatInterval  = 1 * time.Hour
+)
+
+// UpdatePolicy decides whether a newly aggregated price is worth the cost
+// of an on-chain write, based on how far it has moved since the last
+// on-chain update for that symbol and how long it has been since one was
+// last posted. Thresholds are per-symbol since each feed in the
+// FeedRegistry may tolerate different drift.
+type UpdatePolicy struct {
+	db *pgxpool.Pool
+}
+
+func NewUpdatePolicy(db *pgxpool.Pool) *UpdatePolicy {
+	return &UpdatePolicy{db: db}
+}
+
+// defaultDeviationThresholdFromEnv and defaultHeartbeatIntervalFromEnv let
+// operators override the package defaults used when a feed's
+// configuration doesn't specify its own thresholds.
+func defaultDeviationThresholdFromEnv() float64 {
+	v := os.Getenv("UPDATE_DEVIATION_THRESHOLD")
+	if v == "" {
+		return defaultDeviationThreshold
+	}
+	parsed, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Printf("⚠️  Invalid UPDATE_DEVIATION_THRESHOLD %q, using default %.4f", v, defaultDeviationThreshold)
+		return defaultDeviationThreshold
+	}
+	return parsed
+}
+
+func defaultHeartbeatIntervalFromEnv() time.Duration {
+	v := os.Getenv("UPDATE_HEARTBEAT_INTERVAL")
+	if v == "" {
+		return defaultHeartbeatInterval
+	}
+	parsed, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("⚠️  Invalid UPDATE_HEARTBEAT_INTERVAL %q, using default %v", v, defaultHeartbeatInterval)
+		return defaultHeartbeatInterval
+	}
+	return parsed
+}
+
+// ShouldUpdate reports whether symbol's on-chain price should be
+// refreshed, along with a human-readable reason suitable for logging.
+func (p *UpdatePolicy) ShouldUpdate(ctx context.Context, symbol string, newPrice, deviationThreshold float64, heartbeatInterval time.Duration) (bool, string, error) {
+	lastPrice, lastUpdatedAt, ok, err := p.lastOnChainUpdate(ctx, symbol)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to load last on-chain update: %w", err)
+	}
+
+	update, reason := evaluateUpdate(newPrice, lastPrice, lastUpdatedAt, ok, deviationThreshold, heartbeatInterval, time.Now())
+	return update, reason, nil
+}
+
+// evaluateUpdate is the pure decision behind ShouldUpdate, split out so it
+// can be unit tested without a database: given the last on-chain price and
+// when it was posted, decide whether newPrice has drifted or aged past the
+// feed's thresholds.
+func evaluateUpdate(newPrice, lastPrice float64, lastUpdatedAt time.Time, hasPrior bool, deviationThreshold float64, heartbeatInterval time.Duration, now time.Time) (bool, string) {
+	if !hasPrior {
+		return true, "no prior on-chain update recorded"
+	}
+
+	deviation := (newPrice - lastPrice) / lastPrice
+	if deviation < 0 {
+		deviation = -deviation
+	}
+
+	if deviation > deviationThreshold {
+		return true, fmt.Sprintf("deviation %.4f%% exceeds threshold %.4f%%", deviation*100, deviationThreshold*100)
+	}
+
+	elapsed := now.Sub(lastUpdatedAt)
+	if elapsed >= heartbeatInterval {
+		return true, fmt.Sprintf("heartbeat interval %v elapsed since last update", elapsed.Round(time.Second))
+	}
+
+	return false, fmt.Sprintf("deviation %.4f%% within threshold and heartbeat interval not yet elapsed (%v remaining)", deviation*100, (heartbeatInterval - elapsed).Round(time.Second))
+}
+
+// RecordOnChainUpdate persists the price and timestamp of a successful
+// on-chain write for symbol so ShouldUpdate survives process restarts.
+func (p *UpdatePolicy) RecordOnChainUpdate(ctx context.Context, symbol string, price float64, updatedAt time.Time) error {
+	query := `
+		INSERT INTO oracle_update_state (symbol, last_price_usd, last_updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (symbol) DO UPDATE
+		SET last_price_usd = EXCLUDED.last_price_usd, last_updated_at = EXCLUDED.last_updated_at
+	`
+
+	_, err := p.db.Exec(ctx, query, symbol, decimal.NewFromFloat(price), updatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record on-chain update state: %w", err)
+	}
+
+	return nil
+}
+
+func (p *UpdatePolicy) lastOnChainUpdate(ctx context.Context, symbol string) (price float64, updatedAt time.Time, ok bool, err error) {
+	query := `SELECT last_price_usd, last_updated_at FROM oracle_update_state WHERE symbol = $1`
+
+	var priceDecimal decimal.Decimal
+	row := p.db.QueryRow(ctx, query, symbol)
+	if scanErr := row.Scan(&priceDecimal, &updatedAt); scanErr != nil {
+		if scanErr == pgx.ErrNoRows {
+			return 0, time.Time{}, false, nil
+		}
+		return 0, time.Time{}, false, scanErr
+	}
+
+	priceFloat, _ := priceDecimal.Float64()
+	return priceFloat, updatedAt, true, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvaluateUpdate(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name               string
+		newPrice           float64
+		lastPrice          float64
+		lastUpdatedAt      time.Time
+		hasPrior           bool
+		deviationThreshold float64
+		heartbeatInterval  time.Duration
+		wantUpdate         bool
+	}{
+		{
+			name:               "no prior update always updates",
+			newPrice:           1.0,
+			hasPrior:           false,
+			deviationThreshold: 0.005,
+			heartbeatInterval:  time.Hour,
+			wantUpdate:         true,
+		},
+		{
+			name:               "within threshold and heartbeat does not update",
+			newPrice:           1.001,
+			lastPrice:          1.0,
+			lastUpdatedAt:      now.Add(-time.Minute),
+			hasPrior:           true,
+			deviationThreshold: 0.005,
+			heartbeatInterval:  time.Hour,
+			wantUpdate:         false,
+		},
+		{
+			name:               "deviation above threshold updates",
+			newPrice:           1.01,
+			lastPrice:          1.0,
+			lastUpdatedAt:      now.Add(-time.Minute),
+			hasPrior:           true,
+			deviationThreshold: 0.005,
+			heartbeatInterval:  time.Hour,
+			wantUpdate:         true,
+		},
+		{
+			name:               "negative deviation above threshold updates",
+			newPrice:           0.99,
+			lastPrice:          1.0,
+			lastUpdatedAt:      now.Add(-time.Minute),
+			hasPrior:           true,
+			deviationThreshold: 0.005,
+			heartbeatInterval:  time.Hour,
+			wantUpdate:         true,
+		},
+		{
+			name:               "heartbeat interval elapsed updates even with no deviation",
+			newPrice:           1.0,
+			lastPrice:          1.0,
+			lastUpdatedAt:      now.Add(-2 * time.Hour),
+			hasPrior:           true,
+			deviationThreshold: 0.005,
+			heartbeatInterval:  time.Hour,
+			wantUpdate:         true,
+		},
+		{
+			name:               "heartbeat interval exactly elapsed updates",
+			newPrice:           1.0,
+			lastPrice:          1.0,
+			lastUpdatedAt:      now.Add(-time.Hour),
+			hasPrior:           true,
+			deviationThreshold: 0.005,
+			heartbeatInterval:  time.Hour,
+			wantUpdate:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUpdate, reason := evaluateUpdate(tt.newPrice, tt.lastPrice, tt.lastUpdatedAt, tt.hasPrior, tt.deviationThreshold, tt.heartbeatInterval, now)
+			if gotUpdate != tt.wantUpdate {
+				t.Errorf("evaluateUpdate() = %v (%s), want %v", gotUpdate, reason, tt.wantUpdate)
+			}
+			if reason == "" {
+				t.Error("evaluateUpdate() returned an empty reason")
+			}
+		})
+	}
+}